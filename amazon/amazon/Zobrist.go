@@ -3,21 +3,90 @@ package amazon
 
 import (
 	"math/rand"
-	"time"
 )
 
-var zobristTable [10][10][4]uint64
+// 默认的随机种子，固定种子保证不同次运行得到相同的哈希值，便于持久化开局库、置换表和棋谱库
+const DefaultZobristSeed int64 = 0x5a0b7157
+
+var (
+	zobristTable [10][10][4]uint64
+	zobristSide  uint64 // 轮到白方行棋时异或的键值
+)
 
 func init() { // Go会自动调用此init函数
-	initZobristTable()
+	initZobristTable(DefaultZobristSeed)
 }
-func initZobristTable() {
-	rand.Seed(time.Now().UnixNano())
+func initZobristTable(seed int64) {
+	r := rand.New(rand.NewSource(seed))
 	for i := 0; i < 10; i++ {
 		for j := 0; j < 10; j++ {
 			for k := 0; k < 4; k++ {
-				zobristTable[i][j][k] = rand.Uint64()
+				zobristTable[i][j][k] = r.Uint64()
 			}
 		}
 	}
+	zobristSide = r.Uint64()
+}
+
+// 使用指定种子重新生成全部键值（含行棋方键值）
+// 之前计算的哈希值和置换表内容随之失效，应在搜索开始前调用
+func SeedZobrist(seed int64) {
+	initZobristTable(seed)
+}
+
+// 计算包含行棋方的哈希值，color为轮到行棋的一方
+func (b *AmazonBoard) HashFor(color int) uint64 {
+	hash := b.Hash()
+	if color == White {
+		hash ^= zobristSide
+	}
+	return hash
+}
+
+// 着法引起的哈希变化量，包括起点、终点、障碍三个格子和行棋方的变化
+// 异或是自身的逆运算，执行和撤销同一着法时使用相同的变化量
+func moveKey(m AmazonMove, piece int) uint64 {
+	from := &zobristTable[m.From.X][m.From.Y]
+	to := &zobristTable[m.To.X][m.To.Y]
+	put := &zobristTable[m.Put.X][m.Put.Y]
+	return from[piece] ^ from[Empty] ^ to[Empty] ^ to[piece] ^ put[Empty] ^ put[Arrow] ^ zobristSide
+}
+
+// HashedBoard 在棋盘之外维护增量更新的哈希值、行棋方和位棋盘，供搜索使用
+type HashedBoard struct {
+	*AmazonBoard
+	Key   uint64         // 当前局面（含行棋方）的哈希值，等于 HashFor(Color)
+	Color int            // 轮到行棋的一方
+	Bits  AmazonBitboard // 与棋盘同步的位棋盘，用于步法生成
+}
+
+// 以color一方行棋创建带哈希的棋盘，只在此处完整计算一次哈希和位棋盘
+func NewHashedBoard(b *AmazonBoard, color int) *HashedBoard {
+	return &HashedBoard{AmazonBoard: b, Key: b.HashFor(color), Color: color, Bits: b.ToBitboard()}
+}
+
+// 执行着法，并增量更新哈希值、行棋方和位棋盘
+func (h *HashedBoard) Move(m AmazonMove) {
+	h.Key ^= moveKey(m, h.AmazonBoard[m.From.X][m.From.Y])
+	h.AmazonBoard.Move(m)
+	h.Bits.Move(m)
+	h.Color = 3 - h.Color
+}
+
+// 撤销着法，并增量恢复哈希值、行棋方和位棋盘
+func (h *HashedBoard) UndoMove(m AmazonMove) {
+	h.Key ^= moveKey(m, h.AmazonBoard[m.To.X][m.To.Y])
+	h.AmazonBoard.UndoMove(m)
+	h.Bits.UndoMove(m)
+	h.Color = 3 - h.Color
+}
+
+// 用位棋盘生成color一方的全部合法着法，追加到buf后返回
+func (h *HashedBoard) GenerateMoves(color int, buf []AmazonMove) []AmazonMove {
+	return h.Bits.GenerateMoves(color, buf)
+}
+
+// 用位棋盘检查color一方是否还有合法着法
+func (h *HashedBoard) HasMoves(color int) bool {
+	return h.Bits.HasMoves(color)
 }
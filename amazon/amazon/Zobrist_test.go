@@ -0,0 +1,70 @@
+package amazon
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestZobristSeed(t *testing.T) {
+	defer SeedZobrist(DefaultZobristSeed)
+	// 默认种子下初始局面的哈希值固定，修改键值的生成方式会使已保存的开局库和棋谱库失效
+	const black, white uint64 = 0x6a687c43c3d2cbd0, 0x92cf7e2755b7e32e
+	b := NewBoard()
+	if got := b.HashFor(Black); got != black {
+		t.Fatalf("initial hash %#x, want %#x", got, black)
+	}
+	if got := b.HashFor(White); got != white || got != black^zobristSide {
+		t.Fatalf("initial hash with white to move %#x, want %#x", got, white)
+	}
+
+	table, side := zobristTable, zobristSide
+	SeedZobrist(DefaultZobristSeed + 1)
+	if zobristTable == table || zobristSide == side || b.HashFor(Black) == black {
+		t.Fatal("SeedZobrist did not change the keys")
+	}
+	SeedZobrist(DefaultZobristSeed)
+	if zobristTable != table || zobristSide != side {
+		t.Fatal("reseeding with the default seed did not restore the keys")
+	}
+}
+
+func TestHashedBoardMoveSequence(t *testing.T) {
+	r := rand.New(rand.NewSource(9))
+	for _, start := range []int{Black, White} {
+		for game := 0; game < 10; game++ {
+			b := NewBoard()
+			h := NewHashedBoard(b, start)
+			initial := h.Key
+			// 随机走子并不时撤销，每一步后增量哈希都等于完整重算的结果
+			var played []AmazonMove
+			for step := 0; step < 120; step++ {
+				if len(played) > 0 && r.Intn(4) == 0 {
+					h.UndoMove(played[len(played)-1])
+					played = played[:len(played)-1]
+				} else {
+					moves := h.GenerateMoves(h.Color, nil)
+					if len(moves) == 0 {
+						break
+					}
+					m := moves[r.Intn(len(moves))]
+					h.Move(m)
+					played = append(played, m)
+				}
+				wantColor := start
+				if len(played)%2 == 1 {
+					wantColor = 3 - start
+				}
+				if h.Color != wantColor || h.Key != b.HashFor(h.Color) {
+					t.Fatalf("start %d game %d step %d: key %#x color %d, want %#x color %d",
+						start, game, step, h.Key, h.Color, b.HashFor(wantColor), wantColor)
+				}
+			}
+			for i := len(played) - 1; i >= 0; i-- {
+				h.UndoMove(played[i])
+			}
+			if h.Key != initial || *b != *NewBoard() {
+				t.Fatalf("start %d game %d: undoing all moves did not restore the initial key", start, game)
+			}
+		}
+	}
+}
@@ -3,6 +3,7 @@ package amazon
 
 import (
 	"fmt"
+	"iter"
 	"sync"
 
 	"github.com/tongque0/gotack"
@@ -98,7 +99,7 @@ func (m AmazonMove) String() string {
 func (b *AmazonBoard) PrintMoveBoard() {
 	for i := 0; i < len(*b); i++ {
 		for j := 0; j < len((*b)[i]); j++ {
-			if (*b)[i][j] == 100 { // 假设使用100表示不可达的位置
+			if (*b)[i][j] == Unreachable { // 不可达的位置
 				fmt.Print(" . ") // 注意这里有两个空格，与下面两位数的步数占位保持一致
 			} else {
 				// 如果步数小于10，则在前面添加一个空格来保持对齐
@@ -114,13 +115,28 @@ func (b *AmazonBoard) PrintMoveBoard() {
 }
 
 /*
-* 生成所有合法移动
+* 生成所有合法移动（gotack.Board接口）
 * 根据当前玩家确定棋子颜色
-* 找出该颜色的所有棋子
-* 为每个棋子并发生成所有可能的移动
-* 收集并返回所有合法移动
+* 按棋盘顺序依次为每个棋子生成移动，结果顺序确定
  */
 func (b *AmazonBoard) GetAllMoves(IsMaxPlayer bool) []gotack.Move {
+	var moves []gotack.Move
+	var color = 1
+	if !IsMaxPlayer {
+		color = 2
+	}
+	for move := range b.Moves(color) {
+		moves = append(moves, move)
+	}
+	return moves
+}
+
+/*
+* 并发生成所有合法移动
+* 为每个棋子启动一个协程，通过通道收集结果，结果顺序不确定
+* 协程和通道的开销大于生成本身，只适合在根节点等调用次数很少的地方使用
+ */
+func (b *AmazonBoard) GetAllMovesParallel(IsMaxPlayer bool) []gotack.Move {
 	var moves []gotack.Move
 	var color = 1
 	if !IsMaxPlayer {
@@ -141,7 +157,10 @@ func (b *AmazonBoard) GetAllMoves(IsMaxPlayer bool) []gotack.Move {
 		wg.Add(1)
 		go func(chess Position) {
 			defer wg.Done()
-			b.generateMovesForChess(chess, moveChan)
+			b.walkMovesForChess(chess, func(move AmazonMove) bool {
+				moveChan <- move
+				return true
+			})
 		}(chess)
 	}
 
@@ -159,6 +178,35 @@ func (b *AmazonBoard) GetAllMoves(IsMaxPlayer bool) []gotack.Move {
 	return moves
 }
 
+// 将color一方的全部合法移动追加到buf后返回，顺序确定；buf容量足够时不分配内存
+func (b *AmazonBoard) GenerateMoves(color int, buf []AmazonMove) []AmazonMove {
+	for i := 0; i < 10; i++ {
+		for j := 0; j < 10; j++ {
+			if b[i][j] == color {
+				b.walkMovesForChess(Position{i, j}, func(move AmazonMove) bool {
+					buf = append(buf, move)
+					return true
+				})
+			}
+		}
+	}
+	return buf
+}
+
+// 以迭代器形式逐个产生color一方的合法移动，顺序与 GenerateMoves 相同
+// 迭代期间不能修改棋盘
+func (b *AmazonBoard) Moves(color int) iter.Seq[AmazonMove] {
+	return func(yield func(AmazonMove) bool) {
+		for i := 0; i < 10; i++ {
+			for j := 0; j < 10; j++ {
+				if b[i][j] == color && !b.walkMovesForChess(Position{i, j}, yield) {
+					return
+				}
+			}
+		}
+	}
+}
+
 // 执行移动操作
 func (b *AmazonBoard) Move(move gotack.Move) {
 	m, ok := move.(AmazonMove)
@@ -179,26 +227,42 @@ func (b *AmazonBoard) UndoMove(move gotack.Move) {
 		fmt.Println("Invalid move type")
 		return
 	}
-	b[m.From.X][m.From.Y] = b[m.To.X][m.To.Y] // 恢复棋子位置
-	b[m.To.X][m.To.Y] = Empty                 // 清空移动后位置
-	if b[m.From.X] != b[m.Put.X] || b[m.From.Y] != b[m.Put.Y] {
-		b[m.Put.X][m.Put.Y] = Empty // 移除障碍
-	}
+	piece := b[m.To.X][m.To.Y]
+	b[m.Put.X][m.Put.Y] = Empty   // 移除障碍，须先于恢复棋子，障碍可能落在起点上
+	b[m.To.X][m.To.Y] = Empty     // 清空移动后位置
+	b[m.From.X][m.From.Y] = piece // 恢复棋子位置
 }
 
-// 检查游戏是否结束
+// 检查游戏是否结束，任意一方没有合法的移动即结束
 func (b *AmazonBoard) IsGameOver() bool {
-	// 检查黑方是否还有合法的移动
-	blackMoves := b.GetAllMoves(true) // 假设true代表黑方
-	if len(blackMoves) == 0 {
-		return true // 黑方没有合法的移动，游戏结束
-	}
+	return !b.HasMoves(Black) || !b.HasMoves(White)
+}
 
-	// 检查白方是否还有合法的移动
-	whiteMoves := b.GetAllMoves(false) // 假设false代表白方
+// 检查指定颜色一方是否还有合法移动
+// 棋子只要有一个相邻空位，就能移动过去并把障碍射回原位置
+func (b *AmazonBoard) HasMoves(color int) bool {
+	for _, chess := range b.getAllChess(color) {
+		for _, d := range dir {
+			x, y := chess.X+d[0], chess.Y+d[1]
+			if b.legal(x, y) && b[x][y] == Empty {
+				return true
+			}
+		}
+	}
+	return false
+}
 
-	// 直接返回白方移动列表长度是否为0的结果
-	return len(whiteMoves) == 0
+// 统计棋盘上的空位数
+func (b *AmazonBoard) CountEmpty() int {
+	n := 0
+	for i := 0; i < 10; i++ {
+		for j := 0; j < 10; j++ {
+			if b[i][j] == Empty {
+				n++
+			}
+		}
+	}
+	return n
 }
 
 // 检查位置是否合法
@@ -219,8 +283,8 @@ func (b *AmazonBoard) getAllChess(color int) []Position {
 	return positions
 }
 
-// 为单个棋子生成所有合法移动
-func (b *AmazonBoard) generateMovesForChess(chess Position, moveChan chan AmazonMove) {
+// 为单个棋子逐个产生所有合法移动，yield返回false时停止并返回false
+func (b *AmazonBoard) walkMovesForChess(chess Position, yield func(AmazonMove) bool) bool {
 	// 遍历所有方向
 	for j := 0; j < 8; j++ {
 		// 初始方向
@@ -232,13 +296,15 @@ func (b *AmazonBoard) generateMovesForChess(chess Position, moveChan chan Amazon
 				ax, ay := x+dir[k][0], y+dir[k][1]
 				// 沿着当前方向一直移动，寻找可放置箭的位置
 				for b.legal(ax, ay) && ((*b)[ax][ay] == Empty || ax == chess.X && ay == chess.Y) {
-					// 创建合法移动对象，并发送到通道中
+					// 创建合法移动对象
 					move := AmazonMove{
 						From: Position{X: chess.X, Y: chess.Y},
 						To:   Position{X: x, Y: y},
 						Put:  Position{X: ax, Y: ay},
 					}
-					moveChan <- move
+					if !yield(move) {
+						return false
+					}
 					// 继续沿着当前方向
 					ax += dir[k][0]
 					ay += dir[k][1]
@@ -249,6 +315,7 @@ func (b *AmazonBoard) generateMovesForChess(chess Position, moveChan chan Amazon
 			y += dir[j][1]
 		}
 	}
+	return true
 }
 
 // 评估函数接口
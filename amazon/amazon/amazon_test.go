@@ -0,0 +1,189 @@
+package amazon
+
+import (
+	"errors"
+	"math/rand"
+	"slices"
+	"testing"
+)
+
+// 从初始局面随机走若干步得到测试局面
+func randomPosition(r *rand.Rand, plies int) (*AmazonBoard, int) {
+	b := NewBoard()
+	color := Black
+	for i := 0; i < plies; i++ {
+		moves := b.GenerateMoves(color, nil)
+		if len(moves) == 0 {
+			break
+		}
+		b.Move(moves[r.Intn(len(moves))])
+		color = 3 - color
+	}
+	return b, color
+}
+
+func TestMoveUndoMoveIdentity(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 100; i++ {
+		b, color := randomPosition(r, r.Intn(70))
+		before := *b
+		hash := b.Hash()
+		for _, m := range b.GenerateMoves(color, nil) {
+			b.Move(m)
+			b.UndoMove(m)
+			if *b != before {
+				t.Fatalf("position %d: Move/UndoMove %v did not restore the board", i, m)
+			}
+			if got := b.Hash(); got != hash {
+				t.Fatalf("position %d: hash after Move/UndoMove %v = %x, want %x", i, m, got, hash)
+			}
+		}
+	}
+}
+
+func TestUndoMoveArrowOnVacatedSquare(t *testing.T) {
+	b := NewBoard()
+	before := *b
+	// 黑棋从(9,3)走到(5,3)，障碍射回起点(9,3)
+	m := AmazonMove{From: Position{9, 3}, To: Position{5, 3}, Put: Position{9, 3}}
+	b.Move(m)
+	if b[9][3] != Arrow || b[5][3] != Black {
+		t.Fatalf("Move did not place piece and arrow")
+	}
+	b.UndoMove(m)
+	if *b != before {
+		t.Fatalf("UndoMove did not restore the board")
+	}
+}
+
+func TestHashedBoardIncrementalKey(t *testing.T) {
+	r := rand.New(rand.NewSource(2))
+	for i := 0; i < 50; i++ {
+		b, color := randomPosition(r, r.Intn(70))
+		h := NewHashedBoard(b, color)
+		key := h.Key
+		for _, m := range b.GenerateMoves(color, nil) {
+			h.Move(m)
+			if want := b.HashFor(h.Color); h.Key != want {
+				t.Fatalf("position %d: incremental key after %v = %x, want %x", i, m, h.Key, want)
+			}
+			if h.Bits != b.ToBitboard() {
+				t.Fatalf("position %d: bitboard out of sync after %v", i, m)
+			}
+			h.UndoMove(m)
+			if h.Key != key || h.Color != color || h.Bits != b.ToBitboard() {
+				t.Fatalf("position %d: key not restored after undoing %v", i, m)
+			}
+		}
+	}
+}
+
+func TestParseLegalMoveErrors(t *testing.T) {
+	// 初始局面黑方走棋，黑棋位于(6,0)、(6,9)、(9,3)、(9,6)，白棋位于(0,3)、(0,6)、(3,0)、(3,9)
+	tests := []struct {
+		name string
+		move string
+		err  error // nil表示合法
+	}{
+		{"empty", "", ErrMoveFormat},
+		{"short", "DJDF", ErrMoveFormat},
+		{"long", "DJDFDJA", ErrMoveFormat},
+		{"lower case", "djdfdj", ErrMoveFormat},
+		{"garbled", "DJ?FDJ", ErrMoveFormat},
+		{"column out of range", "KJDFDJ", ErrOutOfBoard},
+		{"row out of range", "DJDZDJ", ErrOutOfBoard},
+		{"enemy source", "DADBDC", ErrNotOwnPiece},
+		{"empty source", "FFFGFH", ErrNotOwnPiece},
+		{"piece over piece", "DJHJHI", ErrPathBlocked},
+		{"not a queen line", "DJEHEG", ErrPathBlocked},
+		{"onto a piece", "DJDADB", ErrPathBlocked},
+		{"arrow over piece", "DJEJHJ", ErrArrowBlocked},
+		{"arrow onto piece", "DJDFDA", ErrArrowBlocked},
+		{"arrow onto origin", "DJDFDJ", nil},
+		{"arrow through origin", "AGAEAI", nil},
+	}
+	b := NewBoard()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := b.ParseLegalMove(tt.move, Black)
+			if tt.err == nil {
+				if err != nil {
+					t.Fatalf("ParseLegalMove(%q) = %v, want legal", tt.move, err)
+				}
+				if m.Notation() != tt.move {
+					t.Errorf("Notation() = %q, want %q", m.Notation(), tt.move)
+				}
+				return
+			}
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("ParseLegalMove(%q) = %v, want %v", tt.move, err, tt.err)
+			}
+			var me *MoveError
+			if !errors.As(err, &me) || me.Move != tt.move {
+				t.Errorf("error %v does not name the move %q", err, tt.move)
+			}
+			// 格式和坐标错误由ParseMove报告，棋规错误由CheckMove报告
+			_, perr := ParseMove(tt.move)
+			if syntax := tt.err == ErrMoveFormat || tt.err == ErrOutOfBoard; (perr != nil) != syntax {
+				t.Errorf("ParseMove(%q) = %v", tt.move, perr)
+			}
+		})
+	}
+	if *b != *NewBoard() {
+		t.Error("ParseLegalMove changed the board")
+	}
+}
+
+func TestCheckMoveOutOfBoard(t *testing.T) {
+	b := NewBoard()
+	for _, m := range []AmazonMove{
+		{From: Position{-1, 3}, To: Position{5, 3}, Put: Position{5, 4}},
+		{From: Position{9, 3}, To: Position{10, 3}, Put: Position{5, 4}},
+		{From: Position{9, 3}, To: Position{5, 3}, Put: Position{5, 10}},
+	} {
+		if err := b.CheckMove(m, Black); !errors.Is(err, ErrOutOfBoard) {
+			t.Errorf("CheckMove(%v) = %v, want %v", m, err, ErrOutOfBoard)
+		}
+	}
+}
+
+func TestGenerateMovesNoAllocs(t *testing.T) {
+	r := rand.New(rand.NewSource(5))
+	b, color := randomPosition(r, 10)
+	buf := make([]AmazonMove, 0, 4096)
+	if n := testing.AllocsPerRun(100, func() { buf = b.GenerateMoves(color, buf[:0]) }); n != 0 {
+		t.Errorf("GenerateMoves with a preallocated buffer: %v allocs per run, want 0", n)
+	}
+	p := b.ToBitboard()
+	if n := testing.AllocsPerRun(100, func() { buf = p.GenerateMoves(color, buf[:0]) }); n != 0 {
+		t.Errorf("bitboard GenerateMoves with a preallocated buffer: %v allocs per run, want 0", n)
+	}
+}
+
+func TestMovesOrder(t *testing.T) {
+	r := rand.New(rand.NewSource(6))
+	for i := 0; i < 100; i++ {
+		b, color := randomPosition(r, r.Intn(90))
+		want := b.GenerateMoves(color, nil)
+		var got []AmazonMove
+		for m := range b.Moves(color) {
+			got = append(got, m)
+		}
+		if !slices.Equal(got, want) {
+			t.Fatalf("position %d: Moves yielded %d moves, GenerateMoves %d, or in a different order", i, len(got), len(want))
+		}
+		// 提前结束迭代时只产生已取出的着法
+		if len(want) > 3 {
+			got = got[:0]
+			for m := range b.Moves(color) {
+				got = append(got, m)
+				if len(got) == 3 {
+					break
+				}
+			}
+			if !slices.Equal(got, want[:3]) {
+				t.Fatalf("position %d: Moves with break yielded %v, want %v", i, got, want[:3])
+			}
+		}
+	}
+}
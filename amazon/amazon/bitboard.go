@@ -0,0 +1,340 @@
+// 实现亚马逊棋的128位位棋盘表示，单个格子的射线查预计算的射线表，多个格子的洪泛用移位和掩码，搜索中的步法生成即在其上进行。
+package amazon
+
+import (
+	"math/bits"
+	"slices"
+)
+
+// Bitboard 为10x10棋盘的位集，(x,y)对应第x*10+y位，只使用低100位
+type Bitboard struct {
+	Lo uint64 // 第0-63位
+	Hi uint64 // 第64-99位
+}
+
+var (
+	fullBoard = Bitboard{Lo: ^uint64(0), Hi: 1<<36 - 1} // 全部100个格子
+	notCol0   Bitboard                                  // 除第0列外的格子
+	notCol9   Bitboard                                  // 除第9列外的格子
+)
+
+var (
+	dirShift  [8]int           // 方向数组对应的位移量，与dir的顺序一致
+	rays      [100][8]Bitboard // 从每个格子沿各方向射出、不含起点的整条射线
+	squarePos [100]Position    // 格子编号对应的坐标
+)
+
+func init() {
+	col0, col9 := Bitboard{}, Bitboard{}
+	for x := 0; x < 10; x++ {
+		col0 = col0.Or(SquareBit(x*10 + 0))
+		col9 = col9.Or(SquareBit(x*10 + 9))
+	}
+	notCol0 = fullBoard.AndNot(col0)
+	notCol9 = fullBoard.AndNot(col9)
+	for i, d := range dir {
+		dirShift[i] = d[0]*10 + d[1]
+	}
+	for sq := 0; sq < 100; sq++ {
+		squarePos[sq] = Position{X: sq / 10, Y: sq % 10}
+		for i, d := range dir {
+			for x, y := sq/10+d[0], sq%10+d[1]; x >= 0 && x < 10 && y >= 0 && y < 10; x, y = x+d[0], y+d[1] {
+				rays[sq][i] = rays[sq][i].Or(SquareBit(x*10 + y))
+			}
+		}
+	}
+}
+
+// 只包含sq一个格子的位集
+func SquareBit(sq int) Bitboard {
+	if sq < 64 {
+		return Bitboard{Lo: 1 << sq}
+	}
+	return Bitboard{Hi: 1 << (sq - 64)}
+}
+
+func (b Bitboard) Or(o Bitboard) Bitboard     { return Bitboard{b.Lo | o.Lo, b.Hi | o.Hi} }
+func (b Bitboard) And(o Bitboard) Bitboard    { return Bitboard{b.Lo & o.Lo, b.Hi & o.Hi} }
+func (b Bitboard) AndNot(o Bitboard) Bitboard { return Bitboard{b.Lo &^ o.Lo, b.Hi &^ o.Hi} }
+func (b Bitboard) Not() Bitboard              { return fullBoard.AndNot(b) }
+func (b Bitboard) IsZero() bool               { return b.Lo == 0 && b.Hi == 0 }
+func (b Bitboard) Count() int                 { return bits.OnesCount64(b.Lo) + bits.OnesCount64(b.Hi) }
+
+// 检查sq是否在位集中
+func (b Bitboard) Has(sq int) bool {
+	if sq < 64 {
+		return b.Lo&(1<<sq) != 0
+	}
+	return b.Hi&(1<<(sq-64)) != 0
+}
+
+// 取出并清除最低位的格子，位集为空时返回-1
+func (b *Bitboard) PopLSB() int {
+	switch {
+	case b.Lo != 0:
+		sq := bits.TrailingZeros64(b.Lo)
+		b.Lo &= b.Lo - 1
+		return sq
+	case b.Hi != 0:
+		sq := bits.TrailingZeros64(b.Hi)
+		b.Hi &= b.Hi - 1
+		return sq + 64
+	}
+	return -1
+}
+
+// 最高位的格子，位集为空时返回-1
+func (b Bitboard) msb() int {
+	if b.Hi != 0 {
+		return 127 - bits.LeadingZeros64(b.Hi)
+	}
+	return 63 - bits.LeadingZeros64(b.Lo)
+}
+
+// 最低位的格子，位集为空时返回-1
+func (b Bitboard) lsb() int {
+	if b.Lo != 0 {
+		return bits.TrailingZeros64(b.Lo)
+	}
+	if b.Hi != 0 {
+		return 64 + bits.TrailingZeros64(b.Hi)
+	}
+	return -1
+}
+
+/*
+* 从单个格子sq出发，一步女王走法能到达的所有空格，occupied为全部被占据的格子
+* 查表得到各方向的整条射线，遇到阻挡时去掉最近阻挡格及其后的部分：
+* 位移量为正的方向上最近的阻挡格是最低位，为负的方向上是最高位
+ */
+func queenAttacks(sq int, occupied Bitboard) Bitboard {
+	var reach Bitboard
+	for i := range dir {
+		ray := rays[sq][i]
+		if blockers := ray.And(occupied); !blockers.IsZero() {
+			var b int
+			if dirShift[i] > 0 {
+				b = blockers.lsb()
+			} else {
+				b = blockers.msb()
+			}
+			ray = ray.AndNot(rays[b][i]).AndNot(SquareBit(b))
+		}
+		reach = reach.Or(ray)
+	}
+	return reach
+}
+
+// 128位左移n位（0<n<64），结果截断到100位
+func (b Bitboard) shl(n int) Bitboard {
+	return Bitboard{Lo: b.Lo << n, Hi: b.Hi<<n | b.Lo>>(64-n)}.And(fullBoard)
+}
+
+// 128位右移n位（0<n<64）
+func (b Bitboard) shr(n int) Bitboard {
+	return Bitboard{Lo: b.Lo>>n | b.Hi<<(64-n), Hi: b.Hi >> n}
+}
+
+// 将位集整体沿dir[i]方向平移一格，越过左右边界的格子被丢弃
+func (b Bitboard) shift(i int) Bitboard {
+	var r Bitboard
+	if n := dirShift[i]; n > 0 {
+		r = b.shl(n)
+	} else {
+		r = b.shr(-n)
+	}
+	switch dir[i][1] {
+	case 1:
+		r = r.And(notCol0) // 向右移动时第9列会卷到下一行第0列
+	case -1:
+		r = r.And(notCol9) // 向左移动时第0列会卷到上一行第9列
+	}
+	return r
+}
+
+// 从from中任意格子出发，沿八个方向穿过empty一步女王走法能到达的所有格子
+func QueenReach(from, empty Bitboard) Bitboard {
+	var reach Bitboard
+	for i := range dir {
+		ray := from.shift(i).And(empty)
+		for !ray.IsZero() {
+			reach = reach.Or(ray)
+			ray = ray.shift(i).And(empty)
+		}
+	}
+	return reach
+}
+
+// 从from中任意格子出发，一步国王走法能到达的所有空格
+func KingReach(from, empty Bitboard) Bitboard {
+	var reach Bitboard
+	for i := range dir {
+		reach = reach.Or(from.shift(i))
+	}
+	return reach.And(empty)
+}
+
+// AmazonBitboard 为位棋盘形式的局面
+type AmazonBitboard struct {
+	Black    Bitboard // 黑方棋子
+	White    Bitboard // 白方棋子
+	Arrows   Bitboard // 障碍
+	Occupied Bitboard // 以上三者之并
+}
+
+// 转换为位棋盘
+func (b *AmazonBoard) ToBitboard() AmazonBitboard {
+	var p AmazonBitboard
+	for x := 0; x < 10; x++ {
+		for y := 0; y < 10; y++ {
+			sq := SquareBit(x*10 + y)
+			switch b[x][y] {
+			case Black:
+				p.Black = p.Black.Or(sq)
+			case White:
+				p.White = p.White.Or(sq)
+			case Arrow:
+				p.Arrows = p.Arrows.Or(sq)
+			}
+		}
+	}
+	p.Occupied = p.Black.Or(p.White).Or(p.Arrows)
+	return p
+}
+
+// 转换回二维数组棋盘
+func (p *AmazonBitboard) ToBoard() *AmazonBoard {
+	b := &AmazonBoard{}
+	for x := 0; x < 10; x++ {
+		for y := 0; y < 10; y++ {
+			sq := x*10 + y
+			switch {
+			case p.Black.Has(sq):
+				b[x][y] = Black
+			case p.White.Has(sq):
+				b[x][y] = White
+			case p.Arrows.Has(sq):
+				b[x][y] = Arrow
+			}
+		}
+	}
+	return b
+}
+
+// 所有空格
+func (p *AmazonBitboard) Empty() Bitboard {
+	return p.Occupied.Not()
+}
+
+// 指定颜色一方的棋子
+func (p *AmazonBitboard) Pieces(color int) Bitboard {
+	if color == Black {
+		return p.Black
+	}
+	return p.White
+}
+
+// 执行着法
+func (p *AmazonBitboard) Move(m AmazonMove) {
+	from, to, put := SquareBit(m.From.X*10+m.From.Y), SquareBit(m.To.X*10+m.To.Y), SquareBit(m.Put.X*10+m.Put.Y)
+	if p.Black.And(from).IsZero() {
+		p.White = p.White.AndNot(from).Or(to)
+	} else {
+		p.Black = p.Black.AndNot(from).Or(to)
+	}
+	p.Arrows = p.Arrows.Or(put)
+	p.Occupied = p.Occupied.AndNot(from).Or(to).Or(put)
+}
+
+// 撤销着法
+func (p *AmazonBitboard) UndoMove(m AmazonMove) {
+	from, to, put := SquareBit(m.From.X*10+m.From.Y), SquareBit(m.To.X*10+m.To.Y), SquareBit(m.Put.X*10+m.Put.Y)
+	p.Arrows = p.Arrows.AndNot(put)
+	if p.Black.And(to).IsZero() {
+		p.White = p.White.AndNot(to).Or(from)
+	} else {
+		p.Black = p.Black.AndNot(to).Or(from)
+	}
+	p.Occupied = p.Occupied.AndNot(put).AndNot(to).Or(from)
+}
+
+// 将指定颜色一方的全部合法着法追加到buf后返回，顺序确定
+func (p *AmazonBitboard) GenerateMoves(color int, buf []AmazonMove) []AmazonMove {
+	return p.generateMovesFrom(p.Pieces(color), buf)
+}
+
+// 只为pieces中的棋子生成着法
+func (p *AmazonBitboard) generateMovesFrom(pieces Bitboard, buf []AmazonMove) []AmazonMove {
+	for !pieces.IsZero() {
+		from := pieces.PopLSB()
+		// 棋子离开起点后起点变为空位，障碍可以落在或穿过起点
+		others := p.Occupied.AndNot(SquareBit(from))
+		for tos := queenAttacks(from, p.Occupied); !tos.IsZero(); {
+			to := tos.PopLSB()
+			puts := queenAttacks(to, others.Or(SquareBit(to)))
+			// 先按障碍数一次扩容，再直接写入各着法，省去逐个append的检查
+			n := len(buf)
+			buf = slices.Grow(buf, puts.Count())[:n+puts.Count()]
+			m := AmazonMove{From: squarePos[from], To: squarePos[to]}
+			for i := range buf[n:] {
+				m.Put = squarePos[puts.PopLSB()]
+				buf[n+i] = m
+			}
+		}
+	}
+	return buf
+}
+
+// 指定颜色一方是否还有合法着法：任一棋子有相邻空格即可移动并把障碍射回起点
+func (p *AmazonBitboard) HasMoves(color int) bool {
+	return !KingReach(p.Pieces(color), p.Empty()).IsZero()
+}
+
+// 距离图中不可达格子的取值
+const Unreachable = 100
+
+/*
+* 用洪泛逐层计算指定颜色一方到达每个空格所需的最少步数
+* reach为单步扩展方式（QueenReach或KingReach），路径被障碍和所有棋子阻挡
+* 不可达的格子取值为Unreachable
+ */
+func (p *AmazonBitboard) Distances(color int, reach func(from, empty Bitboard) Bitboard) [100]int {
+	var dist [100]int
+	for i := range dist {
+		dist[i] = Unreachable
+	}
+	empty := p.Empty()
+	frontier := p.Pieces(color)
+	visited := frontier
+	for d := 1; ; d++ {
+		frontier = reach(frontier, empty).AndNot(visited)
+		if frontier.IsZero() {
+			return dist
+		}
+		visited = visited.Or(frontier)
+		for layer := frontier; !layer.IsZero(); {
+			dist[layer.PopLSB()] = d
+		}
+	}
+}
+
+/*
+* 按reach定义的距离统计领土
+* 双方同时逐层洪泛，先到达某空格的一方占有该格，同时到达的格子计入tied
+* 任何一方都无法到达的格子不计入
+ */
+func (p *AmazonBitboard) Territory(reach func(from, empty Bitboard) Bitboard) (black, white, tied int) {
+	empty := p.Empty()
+	frontB, frontW := p.Black, p.White
+	seenB, seenW := frontB, frontW
+	for !frontB.IsZero() || !frontW.IsZero() {
+		frontB = reach(frontB, empty).AndNot(seenB)
+		frontW = reach(frontW, empty).AndNot(seenW)
+		black += frontB.AndNot(seenW).AndNot(frontW).Count()
+		white += frontW.AndNot(seenB).AndNot(frontB).Count()
+		tied += frontB.And(frontW).AndNot(seenB).AndNot(seenW).Count()
+		seenB = seenB.Or(frontB)
+		seenW = seenW.Or(frontW)
+	}
+	return black, white, tied
+}
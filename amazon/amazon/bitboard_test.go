@@ -0,0 +1,189 @@
+package amazon
+
+import (
+	"math/rand"
+	"slices"
+	"testing"
+)
+
+// 按着法的格子编号排序，用于比较两种步法生成器的着法集合
+func sortMoves(moves []AmazonMove) {
+	key := func(m AmazonMove) int {
+		return ((m.From.X*10+m.From.Y)*100+m.To.X*10+m.To.Y)*100 + m.Put.X*10 + m.Put.Y
+	}
+	slices.SortFunc(moves, func(a, b AmazonMove) int { return key(a) - key(b) })
+}
+
+// 在二维数组棋盘上逐格广度优先计算color一方的距离，作为位棋盘洪泛的参照；king为true时每步只走一格
+func referenceDistances(b *AmazonBoard, color int, king bool) [100]int {
+	var dist [100]int
+	for i := range dist {
+		dist[i] = Unreachable
+	}
+	var queue []Position
+	for x := 0; x < 10; x++ {
+		for y := 0; y < 10; y++ {
+			if b[x][y] == color {
+				queue = append(queue, Position{x, y})
+			}
+		}
+	}
+	depth := map[Position]int{}
+	for len(queue) > 0 {
+		p := queue[0]
+		queue = queue[1:]
+		for _, d := range dir {
+			for x, y := p.X+d[0], p.Y+d[1]; b.legal(x, y) && b[x][y] == Empty; x, y = x+d[0], y+d[1] {
+				if dist[x*10+y] == Unreachable {
+					dist[x*10+y] = depth[p] + 1
+					depth[Position{x, y}] = depth[p] + 1
+					queue = append(queue, Position{x, y})
+				}
+				if king {
+					break
+				}
+			}
+		}
+	}
+	return dist
+}
+
+// 按参照距离统计领土：距离较近的一方占有该格，距离相等且可达时计入tied
+func referenceTerritory(black, white [100]int) (b, w, tied int) {
+	for i := range black {
+		switch {
+		case black[i] < white[i]:
+			b++
+		case white[i] < black[i]:
+			w++
+		case black[i] != Unreachable:
+			tied++
+		}
+	}
+	return b, w, tied
+}
+
+func TestBitboardRoundTrip(t *testing.T) {
+	r := rand.New(rand.NewSource(7))
+	for i := 0; i < 200; i++ {
+		b, color := randomPosition(r, r.Intn(90))
+		p := b.ToBitboard()
+		if got := p.ToBoard(); *got != *b {
+			t.Fatalf("position %d: ToBitboard/ToBoard did not round trip", i)
+		}
+		if got, want := p.Empty().Count(), b.CountEmpty(); got != want {
+			t.Fatalf("position %d: %d empty squares, want %d", i, got, want)
+		}
+		// 位棋盘上的Move/UndoMove与数组棋盘保持一致
+		moves := b.GenerateMoves(color, nil)
+		if len(moves) == 0 {
+			continue
+		}
+		m := moves[r.Intn(len(moves))]
+		p.Move(m)
+		b.Move(m)
+		if got := p.ToBoard(); *got != *b {
+			t.Fatalf("position %d: bitboard after %v differs from the board", i, m)
+		}
+		p.UndoMove(m)
+		b.UndoMove(m)
+		if got := p.ToBoard(); *got != *b {
+			t.Fatalf("position %d: bitboard after undoing %v differs from the board", i, m)
+		}
+	}
+}
+
+func TestBitboardGenerateMoves(t *testing.T) {
+	r := rand.New(rand.NewSource(8))
+	for i := 0; i < 200; i++ {
+		b, color := randomPosition(r, r.Intn(90))
+		p := b.ToBitboard()
+		got := p.GenerateMoves(color, nil)
+		want := b.GenerateMoves(color, nil)
+		sortMoves(got)
+		sortMoves(want)
+		if !slices.Equal(got, want) {
+			t.Fatalf("position %d: bitboard generated %d moves, board %d", i, len(got), len(want))
+		}
+	}
+}
+
+func TestBitboardDistancesAndTerritory(t *testing.T) {
+	r := rand.New(rand.NewSource(9))
+	for i := 0; i < 200; i++ {
+		b, _ := randomPosition(r, r.Intn(90))
+		p := b.ToBitboard()
+		for _, tc := range []struct {
+			name  string
+			reach func(from, empty Bitboard) Bitboard
+			king  bool
+		}{
+			{"queen", QueenReach, false},
+			{"king", KingReach, true},
+		} {
+			black, white := referenceDistances(b, Black, tc.king), referenceDistances(b, White, tc.king)
+			if got := p.Distances(Black, tc.reach); got != black {
+				t.Fatalf("position %d: black %s distances differ from breadth-first search", i, tc.name)
+			}
+			if got := p.Distances(White, tc.reach); got != white {
+				t.Fatalf("position %d: white %s distances differ from breadth-first search", i, tc.name)
+			}
+			wb, ww, wt := referenceTerritory(black, white)
+			if gb, gw, gt := p.Territory(tc.reach); gb != wb || gw != ww || gt != wt {
+				t.Fatalf("position %d: %s territory = %d/%d/%d, want %d/%d/%d", i, tc.name, gb, gw, gt, wb, ww, wt)
+			}
+		}
+	}
+}
+
+// 在位棋盘上做perft，与数组棋盘的 Perft 计数相同
+func bitboardPerft(p *AmazonBitboard, color, depth int, bufs [][]AmazonMove) int64 {
+	moves := p.GenerateMoves(color, bufs[depth-1][:0])
+	bufs[depth-1] = moves
+	if depth == 1 {
+		return int64(len(moves))
+	}
+	var nodes int64
+	for _, m := range moves {
+		p.Move(m)
+		nodes += bitboardPerft(p, 3-color, depth-1, bufs)
+		p.UndoMove(m)
+	}
+	return nodes
+}
+
+func TestBitboardPerft(t *testing.T) {
+	r := rand.New(rand.NewSource(10))
+	for i := 0; i < 20; i++ {
+		b, color := randomPosition(r, 20+r.Intn(60))
+		p := b.ToBitboard()
+		if got, want := bitboardPerft(&p, color, 2, make([][]AmazonMove, 2)), b.Perft(color, 2); got != want {
+			t.Fatalf("position %d: bitboard perft %d, want %d", i, got, want)
+		}
+	}
+}
+
+// 两种棋盘表示的步法生成速度，以每秒生成的叶子节点数计
+func BenchmarkPerftNPS(b *testing.B) {
+	board := NewBoard()
+	board.Move(AmazonMove{From: Position{9, 3}, To: Position{5, 3}, Put: Position{5, 7}})
+	const depth = 2
+	for _, bench := range []struct {
+		name  string
+		perft func() int64
+	}{
+		{"board", func() int64 { return board.Perft(White, depth) }},
+		{"bitboard", func() int64 {
+			p := board.ToBitboard()
+			return bitboardPerft(&p, White, depth, make([][]AmazonMove, depth))
+		}},
+	} {
+		b.Run(bench.name, func(b *testing.B) {
+			var nodes int64
+			for i := 0; i < b.N; i++ {
+				nodes += bench.perft()
+			}
+			b.ReportMetric(float64(nodes)/b.Elapsed().Seconds(), "nodes/s")
+		})
+	}
+}
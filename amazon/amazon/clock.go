@@ -0,0 +1,42 @@
+// 管理整局比赛的用时，为每一步分配搜索时间。
+package amazon
+
+import "time"
+
+const (
+	DefaultGameTime = 15 * time.Minute       // 默认的单方总用时
+	reserveSquares  = 20                     // 终局时通常仍留在封闭区域内的空位数
+	minMovesLeft    = 8                      // 估计剩余步数的下限，避免后期用时过猛
+	safetyMargin    = 500 * time.Millisecond // 为通信和调度留出的余量
+	minBudget       = 100 * time.Millisecond // 每步预算的下限，保证至少能完成浅层搜索
+)
+
+// Clock 记录本方剩余的总用时
+type Clock struct {
+	Remaining time.Duration
+}
+
+// 创建总用时为total的计时器，total不大于0时使用DefaultGameTime
+func NewClock(total time.Duration) Clock {
+	if total <= 0 {
+		total = DefaultGameTime
+	}
+	return Clock{Remaining: total}
+}
+
+/*
+* 计算本步的时间预算
+* 每一步都会消耗一个空位，本方剩余步数约为(空位数-终局残留空位)/2
+* 将剩余用时平均分配到剩余步数上，并扣除安全余量
+* 用时所剩无几时扣除余量后可能不足minBudget，此时取minBudget，但不超过剩余用时的一半
+ */
+func (c *Clock) Budget(b *AmazonBoard) time.Duration {
+	movesLeft := max((b.CountEmpty()-reserveSquares)/2, minMovesLeft)
+	budget := c.Remaining/time.Duration(movesLeft) - safetyMargin
+	return min(max(budget, minBudget), c.Remaining/2)
+}
+
+// 扣除本步实际使用的时间
+func (c *Clock) Spend(d time.Duration) {
+	c.Remaining = max(c.Remaining-d, 0)
+}
@@ -0,0 +1,71 @@
+package amazon
+
+import (
+	"testing"
+	"time"
+)
+
+// 保留初始局面的棋子，用障碍填满空格直到只剩n个
+func boardWithEmpty(n int) *AmazonBoard {
+	b := NewBoard()
+	for x := 0; x < 10 && b.CountEmpty() > n; x++ {
+		for y := 0; y < 10 && b.CountEmpty() > n; y++ {
+			if b[x][y] == Empty {
+				b[x][y] = Arrow
+			}
+		}
+	}
+	return b
+}
+
+func TestClockBudget(t *testing.T) {
+	tests := []struct {
+		name      string
+		remaining time.Duration
+		empty     int
+		want      time.Duration
+	}{
+		// 初始局面92个空格，约剩36步
+		{"opening", 15 * time.Minute, 92, 15*time.Minute/36 - safetyMargin},
+		{"midgame", 5 * time.Minute, 60, 5*time.Minute/20 - safetyMargin},
+		// 空格不多时按至少8步分配
+		{"endgame", time.Minute, 30, time.Minute/8 - safetyMargin},
+		{"no empty squares", time.Minute, 0, time.Minute/8 - safetyMargin},
+		// 平均分配后不足安全余量，取下限
+		{"low time", 2 * time.Second, 92, minBudget},
+		{"low time endgame", 4 * time.Second, 10, minBudget},
+		// 连下限都用不起时只用剩余用时的一半
+		{"almost out of time", 150 * time.Millisecond, 40, 75 * time.Millisecond},
+		{"out of time", 0, 40, 0},
+	}
+	for _, tt := range tests {
+		c := Clock{Remaining: tt.remaining}
+		b := boardWithEmpty(tt.empty)
+		if b.CountEmpty() != tt.empty {
+			t.Fatalf("%s: board has %d empty squares", tt.name, b.CountEmpty())
+		}
+		got := c.Budget(b)
+		if got != tt.want {
+			t.Errorf("%s: Budget = %v, want %v", tt.name, got, tt.want)
+		}
+		if got < 0 || got > tt.remaining {
+			t.Errorf("%s: Budget %v outside [0, %v]", tt.name, got, tt.remaining)
+		}
+	}
+}
+
+func TestClockSpend(t *testing.T) {
+	c := NewClock(0)
+	if c.Remaining != DefaultGameTime {
+		t.Fatalf("NewClock(0) = %v, want %v", c.Remaining, DefaultGameTime)
+	}
+	c = NewClock(time.Second)
+	c.Spend(300 * time.Millisecond)
+	if c.Remaining != 700*time.Millisecond {
+		t.Fatalf("remaining %v after spending 300ms of 1s", c.Remaining)
+	}
+	c.Spend(time.Second)
+	if c.Remaining != 0 {
+		t.Fatalf("remaining %v after overspending", c.Remaining)
+	}
+}
@@ -5,16 +5,39 @@ import (
 	"github.com/tongque0/gotack"
 )
 
-// 将通用的评估选项转换为特定的 AmazonBoard 评估值，调用 CalculateEvaluationValue 方法计算评分
+/*
+* 将通用的评估选项转换为 AmazonBoard 的评估值（gotack.Board接口）
+* gotack的Alpha-Beta/PVS为极大极小形式，GetAllMoves(true)生成的是黑方着法，即极大方恒为黑方
+* 因此这里始终以黑方视角评估，与引擎执哪一方、opt.IsMaxPlayer的取值无关
+* 引擎自身的负极大值搜索直接使用以行棋方视角计分的 EvaluateWith
+ */
 func EvaluateFunc(opt *gotack.EvalOptions) float64 {
 	// 尝试将board转换为*AmazonBoard类型
 	amazonBoard, ok := opt.Board.(*AmazonBoard)
 	if !ok {
 		return 0.0 // 或者处理这种情况的其他方式
 	}
+	return amazonBoard.CalculateEvaluationValue(opt.Step, Black)
+}
+
+// Evaluator 以color一方视角评估局面（负极大值约定），turn为当前步数
+// 实现必须可被多个协程同时调用
+type Evaluator interface {
+	Evaluate(b *AmazonBoard, turn, color int) float64
+}
 
-	// 假设我们使用AmazonBoard的TurnID属性和isMaxPlayer来调用CalculateEvaluationValue
-	value := amazonBoard.CalculateEvaluationValue(opt.Step, opt.IsMaxPlayer)
-	// 返回评估分数
-	return value
+// 手工评估函数作为 Evaluator
+func (p *EvalParams) Evaluate(b *AmazonBoard, turn, color int) float64 {
+	return b.EvaluateWith(p, turn, color)
+}
+
+// 将任意 Evaluator 包装为gotack的评估函数签名，与 EvaluateFunc 一样以黑方视角计分
+func EvaluateFuncFor(e Evaluator) func(opt *gotack.EvalOptions) float64 {
+	return func(opt *gotack.EvalOptions) float64 {
+		b, ok := opt.Board.(*AmazonBoard)
+		if !ok {
+			return 0.0
+		}
+		return e.Evaluate(b, opt.Step, Black)
+	}
 }
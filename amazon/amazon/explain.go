@@ -0,0 +1,95 @@
+// 拆解评估函数，输出各要素的双方原始值、权重与贡献以及领土归属图，用于调试评估结果。
+package amazon
+
+import (
+	"fmt"
+	"io"
+)
+
+// EvalTerm 为评估函数中一个要素的明细
+type EvalTerm struct {
+	Name   string  // 要素名称
+	Black  float64 // 黑方的原始值
+	White  float64 // 白方的原始值
+	Value  float64 // 参与加权的要素值，由双方原始值得到
+	Weight float64 // 当前阶段的权重
+}
+
+// 该要素对评估值的贡献
+func (t EvalTerm) Contribution() float64 {
+	return t.Value * t.Weight
+}
+
+// ExplainEval 按params在第turn步的权重拆解评估值，各贡献之和即为黑方视角下 EvaluateWith 的结果
+func (b *AmazonBoard) ExplainEval(params *EvalParams, turn int) []EvalTerm {
+	black, white := b.evalSides()
+	value := combineTerms(black, white)
+	w := params.Weights(turn)
+	return []EvalTerm{
+		{"queen_territory", black.QueenTerritory, white.QueenTerritory, value.QueenTerritory, w.QueenTerritory},
+		{"king_territory", black.KingTerritory, white.KingTerritory, value.KingTerritory, w.KingTerritory},
+		{"p1", black.P1, white.P1, value.P1, w.P1},
+		{"p2", black.P2, white.P2, value.P2, w.P2},
+		{"mobility", black.Mobility, white.Mobility, value.Mobility, w.Mobility},
+	}
+}
+
+/*
+* 领土归属图
+* 棋子和障碍照常显示为B、W、X
+* 空格按双方距离显示：黑方更近为b，白方更近为w，距离相同为=，双方都不可达为空格
+ */
+func (b *AmazonBoard) OwnershipMap(black, white AmazonBoard) [10][10]byte {
+	var m [10][10]byte
+	for x := 0; x < 10; x++ {
+		for y := 0; y < 10; y++ {
+			switch {
+			case b[x][y] == Black:
+				m[x][y] = 'B'
+			case b[x][y] == White:
+				m[x][y] = 'W'
+			case b[x][y] == Arrow:
+				m[x][y] = 'X'
+			case black[x][y] < white[x][y]:
+				m[x][y] = 'b'
+			case black[x][y] > white[x][y]:
+				m[x][y] = 'w'
+			case black[x][y] != Unreachable:
+				m[x][y] = '='
+			default:
+				m[x][y] = ' '
+			}
+		}
+	}
+	return m
+}
+
+// WriteEvalReport 输出评估明细表和queen、king两种距离的领土归属图
+func (b *AmazonBoard) WriteEvalReport(w io.Writer, params *EvalParams, turn int) {
+	var total float64
+	fmt.Fprintf(w, "eval turn %d\n", turn)
+	fmt.Fprintf(w, "%-16s %10s %10s %10s %10s %10s\n", "term", "black", "white", "value", "weight", "contrib")
+	for _, t := range b.ExplainEval(params, turn) {
+		total += t.Contribution()
+		fmt.Fprintf(w, "%-16s %10.3f %10.3f %10.3f %10.3f %10.3f\n", t.Name, t.Black, t.White, t.Value, t.Weight, t.Contribution())
+	}
+	fmt.Fprintf(w, "%-16s %54.3f\n", "total", total)
+
+	queenBlack, queenWhite := b.CalculateQueenMoves()
+	kingBlack, kingWhite := b.CalculateKingMoves()
+	queen := b.OwnershipMap(queenBlack, queenWhite)
+	king := b.OwnershipMap(kingBlack, kingWhite)
+	fmt.Fprintf(w, "   %-22s %s\n", "queen", "king")
+	for x := 0; x < 10; x++ {
+		fmt.Fprintf(w, "%2d ", 10-x)
+		for y := 0; y < 10; y++ {
+			fmt.Fprintf(w, "%c ", queen[x][y])
+		}
+		fmt.Fprint(w, "   ")
+		for y := 0; y < 10; y++ {
+			fmt.Fprintf(w, "%c ", king[x][y])
+		}
+		fmt.Fprintln(w)
+	}
+	fmt.Fprintln(w, "   a b c d e f g h i j    a b c d e f g h i j")
+}
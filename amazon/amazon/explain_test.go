@@ -0,0 +1,116 @@
+package amazon
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestExplainEvalSumsToEvaluation(t *testing.T) {
+	r := rand.New(rand.NewSource(8))
+	custom := EvalParams{Phases: []EvalPhase{
+		{Until: 10, QueenTerritory: Weight{Base: 3, Slope: 0.5}, P2: Weight{Base: -1}},
+		{Until: 0, KingTerritory: Weight{Base: 2}, P1: Weight{Slope: 0.1}, Mobility: Weight{Base: 7}},
+	}}
+	for i := 0; i < 50; i++ {
+		b, _ := randomPosition(r, r.Intn(80))
+		for _, params := range []*EvalParams{&DefaultEvalParams, &custom} {
+			turn := 1 + r.Intn(40)
+			terms := b.ExplainEval(params, turn)
+			var sum float64
+			for _, term := range terms {
+				sum += term.Contribution()
+			}
+			if want := b.EvaluateWith(params, turn, Black); !almostEqual(sum, want) {
+				t.Fatalf("position %d turn %d: contributions sum to %v, EvaluateWith %v", i, turn, sum, want)
+			}
+			// 要素值与权重和评估函数使用的一致
+			v, w := b.CalculateEvalTerms(), params.Weights(turn)
+			values := []float64{v.QueenTerritory, v.KingTerritory, v.P1, v.P2, v.Mobility}
+			weights := []float64{w.QueenTerritory, w.KingTerritory, w.P1, w.P2, w.Mobility}
+			for k, term := range terms {
+				if term.Value != values[k] || term.Weight != weights[k] {
+					t.Fatalf("position %d: term %s value %v weight %v, want %v and %v", i, term.Name, term.Value, term.Weight, values[k], weights[k])
+				}
+			}
+		}
+	}
+}
+
+func TestOwnershipMap(t *testing.T) {
+	// 右下角的空格被障碍围住，双方都无法到达；(0,1)黑方一步可达而白方要两步
+	b := parseBoard(t,
+		"B.........",
+		"..........",
+		"..........",
+		"..........",
+		"..........",
+		"..........",
+		"..........",
+		"..........",
+		"........XX",
+		"......W.X.",
+	)
+	queenBlack, queenWhite := b.CalculateQueenMoves()
+	m := b.OwnershipMap(queenBlack, queenWhite)
+	tests := []struct {
+		x, y int
+		want byte
+	}{
+		{0, 0, 'B'},
+		{9, 6, 'W'},
+		{8, 8, 'X'},
+		{9, 9, ' '}, // 被围住的空格
+		{0, 1, 'b'}, // 黑方一步，白方两步
+		{9, 7, 'w'}, // 白方一步，黑方两步
+		{9, 0, '='}, // 双方各一步
+	}
+	for _, tt := range tests {
+		if got := m[tt.x][tt.y]; got != tt.want {
+			t.Errorf("(%d,%d) = %q, want %q (black %d, white %d)", tt.x, tt.y, got, tt.want, queenBlack[tt.x][tt.y], queenWhite[tt.x][tt.y])
+		}
+	}
+	// 每个空格的标记与双方距离的比较一致
+	for x := 0; x < 10; x++ {
+		for y := 0; y < 10; y++ {
+			if b[x][y] != Empty {
+				continue
+			}
+			bd, wd := queenBlack[x][y], queenWhite[x][y]
+			want := byte('=')
+			switch {
+			case bd < wd:
+				want = 'b'
+			case bd > wd:
+				want = 'w'
+			case bd == Unreachable:
+				want = ' '
+			}
+			if m[x][y] != want {
+				t.Fatalf("(%d,%d) = %q with distances %d and %d", x, y, m[x][y], bd, wd)
+			}
+		}
+	}
+}
+
+func TestWriteEvalReport(t *testing.T) {
+	b := NewBoard()
+	var out bytes.Buffer
+	b.WriteEvalReport(&out, &DefaultEvalParams, 5)
+	report := out.String()
+	want := fmt.Sprintf("%.3f", b.EvaluateWith(&DefaultEvalParams, 5, Black))
+	if !strings.HasPrefix(report, "eval turn 5\n") || !strings.Contains(report, "total") {
+		t.Fatalf("report:\n%s", report)
+	}
+	for _, line := range strings.Split(report, "\n") {
+		if fields := strings.Fields(line); len(fields) == 2 && fields[0] == "total" && fields[1] != want {
+			t.Errorf("total %s, want %s", fields[1], want)
+		}
+	}
+	// 明细表5行要素，两张归属图各10行
+	if n := strings.Count(report, "\n"); n != 1+1+5+1+1+10+1 {
+		t.Errorf("report has %d lines:\n%s", n, report)
+	}
+}
@@ -0,0 +1,607 @@
+// 实现针对亚马逊棋改进的蒙特卡洛树搜索(UCT)，各项改进均可单独开关，支持树并行与根并行。
+package amazon
+
+import (
+	"math"
+	"math/rand"
+	"slices"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+/*
+* MCTSOptions 配置一次蒙特卡洛树搜索
+* 以下各项改进均可单独开关，零值表示关闭：
+*   延迟扩展 ExpandThreshold：叶节点被模拟达到该次数后才展开子节点，对应gotack的SimThresh
+*   估值筛选 PruneWidth：展开时按一步静态评估排序，只保留最好的若干着法，对应ExpandTopN
+*   渐进加宽 WidenInterval：子节点按静态评估顺序逐批开放，节点每多WidenInterval次访问开放WidenStep个，
+*     初始开放WidenInitial个，对应ExpandThresh和ExpandStep
+*   提前评估 EarlyCutoff：随机模拟只走CutoffPlies步就用评估函数估计胜率，关闭时模拟到终局，对应AheadStep
+*   动态分配时间 TimeAlloc：领先着法已无法被超越时提前结束，最佳着法不稳定时最多延长到两倍预算
+* Threads大于1时多线程搜索，Parallel选择树并行或根并行
+ */
+type MCTSOptions struct {
+	Step          int           // 当前步数，用于评估函数的阶段权重
+	Budget        time.Duration // 搜索时间预算，0表示只按MaxIterations限制
+	MaxIterations int           // 最多模拟次数（各线程合计），0表示只按Budget限制；两者都为0且没有Stop时只做minIterations次
+	Exploration   float64       // UCB探索系数，0时取DefaultExploration
+	Eval          Evaluator     // 评估器，为nil时使用默认参数的手工评估函数
+	ValueScale    float64       // 评估值转换为胜率的尺度：胜率=1/(1+exp(-评估值/ValueScale))，0时取DefaultValueScale
+	Seed          int64         // 随机模拟的种子，0表示按当前时间
+	Exclude       []AmazonMove  // 根节点需要排除的着法
+	Stop          *atomic.Bool  // 外部停止信号，置位后搜索尽快结束，可为nil；Budget和MaxIterations都为0时只按它停止
+
+	ExpandThreshold int  // 延迟扩展的模拟次数阈值，0或1表示第一次访问即扩展
+	PruneWidth      int  // 估值筛选保留的着法数，0表示不筛选
+	WidenInitial    int  // 渐进加宽初始开放的子节点数
+	WidenStep       int  // 渐进加宽每次开放的子节点数
+	WidenInterval   int  // 渐进加宽的访问次数间隔，0表示关闭渐进加宽
+	EarlyCutoff     bool // 是否提前评估
+	CutoffPlies     int  // 提前评估前随机走的步数
+	TimeAlloc       bool // 是否动态分配时间
+
+	Threads     int          // 搜索线程数，0或1表示单线程
+	Parallel    ParallelMode // 多线程时的并行方式
+	VirtualLoss int          // 树并行时线程经过节点暂记的虚拟失败次数，0时取DefaultVirtualLoss
+}
+
+// ParallelMode 为多线程蒙特卡洛树搜索的并行方式
+type ParallelMode int
+
+const (
+	// 树并行：各线程共同扩展同一棵树，经过的节点暂记虚拟失败，使其他线程倾向于选择别的分支
+	TreeParallel ParallelMode = iota
+	// 根并行：各线程独立建树，结束时按着法合并各棵树根节点的子节点统计
+	RootParallel
+)
+
+const (
+	DefaultExploration = 0.7
+	DefaultValueScale  = 400
+	DefaultVirtualLoss = 3
+
+	maxTimeExtension = 2   // 动态分配时间时最多延长到预算的倍数
+	checkInterval    = 64  // 每个线程每隔多少次模拟检查一次时间
+	minIterations    = 256 // 没有任何停止条件（如用时将尽、时间预算为0）时的模拟次数
+)
+
+// 引擎使用的默认设置，与原先gotack UCT的参数对应
+var DefaultMCTSOptions = MCTSOptions{
+	ExpandThreshold: 40,
+	PruneWidth:      250,
+	WidenInitial:    5,
+	WidenStep:       5,
+	WidenInterval:   1000,
+	EarlyCutoff:     true,
+	CutoffPlies:     6,
+	TimeAlloc:       true,
+}
+
+// MCTSResult 为一次蒙特卡洛树搜索的结果
+type MCTSResult struct {
+	Move       AmazonMove         // 访问次数最多的着法
+	Score      float64            // 该着法的平均胜率，以行棋方视角
+	Visits     map[AmazonMove]int // 根节点各子节点的访问次数，根并行时为各棵树之和
+	Iterations int                // 模拟次数
+	Reused     int                // 从上一次搜索沿用的根节点访问次数
+	Elapsed    time.Duration      // 已用时间
+}
+
+// 可原子累加的浮点数
+type atomicFloat struct {
+	bits atomic.Uint64
+}
+
+func (f *atomicFloat) Load() float64 {
+	return math.Float64frombits(f.bits.Load())
+}
+
+func (f *atomicFloat) Store(v float64) {
+	f.bits.Store(math.Float64bits(v))
+}
+
+func (f *atomicFloat) Add(delta float64) {
+	for {
+		old := f.bits.Load()
+		if f.bits.CompareAndSwap(old, math.Float64bits(math.Float64frombits(old)+delta)) {
+			return
+		}
+	}
+}
+
+// 搜索树节点，访问次数和累计胜率由各线程原子更新，着法列表由mu保护
+type mctsNode struct {
+	move   AmazonMove // 走到本节点的着法
+	color  int        // 本节点的行棋方
+	parent *mctsNode
+	visits atomic.Int64 // 访问次数，树并行时包含尚未撤销的虚拟失败
+	wins   atomicFloat  // 以走到本节点的一方（即父节点行棋方）视角的累计胜率
+
+	mu        sync.Mutex
+	children  []*mctsNode  // 已开放的子节点
+	pending   []AmazonMove // 尚未开放的着法，按静态评估从好到坏排列
+	expanded  bool         // 是否已生成着法
+	expanding bool         // 是否有线程正在生成着法
+	terminal  bool         // 行棋方无子可动
+}
+
+// 平均胜率，节点必须已被访问过
+func (n *mctsNode) mean() float64 {
+	return n.wins.Load() / float64(n.visits.Load())
+}
+
+// 已开放子节点的快照
+func (n *mctsNode) snapshot() []*mctsNode {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return slices.Clone(n.children)
+}
+
+// MCTS 为蒙特卡洛树搜索器，搜索树在多次搜索间保留，经 Advance 走到下一局面后继续使用
+type MCTS struct {
+	opts  MCTSOptions
+	rng   *rand.Rand  // 为各线程生成随机数种子
+	root  *mctsNode   // 最近一次搜索的根节点（经Advance后为对应的子孙节点），根并行时为第一个线程的树
+	board AmazonBoard // root对应的局面
+}
+
+// 每个搜索线程私有的随机数和着法缓冲区
+type mctsWorker struct {
+	t   *MCTS
+	rng *rand.Rand
+	buf []AmazonMove
+}
+
+// 一次搜索中各线程共享的计数
+type mctsRun struct {
+	start      time.Time
+	started    atomic.Int64 // 已开始的模拟次数
+	iterations atomic.Int64 // 已完成的模拟次数
+	stop       atomic.Bool  // 时间已用完
+	reused     int          // 沿用的根节点访问次数
+}
+
+// 按选项创建搜索器，未设置的系数取默认值
+func NewMCTS(opts MCTSOptions) *MCTS {
+	seed := opts.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	t := &MCTS{rng: rand.New(rand.NewSource(seed))}
+	t.SetOptions(opts)
+	return t
+}
+
+// 更换之后各次搜索的选项，保留搜索树和随机数状态（Seed不再生效），未设置的系数取默认值
+func (t *MCTS) SetOptions(opts MCTSOptions) {
+	if opts.Exploration == 0 {
+		opts.Exploration = DefaultExploration
+	}
+	if opts.ValueScale == 0 {
+		opts.ValueScale = DefaultValueScale
+	}
+	if opts.Eval == nil {
+		opts.Eval = &DefaultEvalParams
+	}
+	if opts.VirtualLoss == 0 {
+		opts.VirtualLoss = DefaultVirtualLoss
+	}
+	t.opts = opts
+}
+
+/*
+* 双方走出着法m后调用，使下一次搜索从对应的子节点继续，沿用其下已积累的访问次数和胜率（继承搜索数）
+* 本方着法和对手应着各调用一次，即下行到对应的孙节点
+* 树中没有该着法时丢弃整棵树，返回false
+ */
+func (t *MCTS) Advance(m AmazonMove) bool {
+	if t.root == nil {
+		return false
+	}
+	var next *mctsNode
+	for _, c := range t.root.children {
+		if c.move == m {
+			next = c
+			break
+		}
+	}
+	if next == nil {
+		t.root = nil
+		return false
+	}
+	t.board.Move(m)
+	next.parent = nil // 与旧树断开，其余分支随之释放
+	t.root = next
+	return true
+}
+
+// MCTSCheckpoint 为搜索树的保存点，用于撤销之后的 Advance
+type MCTSCheckpoint struct {
+	root  *mctsNode
+	board AmazonBoard
+}
+
+// 记录当前的根节点和局面，应在 Advance 之前调用
+func (t *MCTS) Checkpoint() MCTSCheckpoint {
+	return MCTSCheckpoint{root: t.root, board: t.board}
+}
+
+/*
+* 回到保存点时的根节点，撤销其后的 Advance，例如本方着法被平台判为错误时
+* Advance 断开的子节点重新挂回原根节点，其间在子节点上继续搜索积累的统计一并保留，
+* 这些模拟没有回传给原根节点，因此按子节点重新统计其访问次数和胜率，使UCT统计保持一致
+* 不能与 Search 同时调用
+ */
+func (t *MCTS) Restore(c MCTSCheckpoint) {
+	t.root, t.board = c.root, c.board
+	if t.root != nil {
+		t.root.relink()
+	}
+}
+
+// 把被 Advance 断开的子节点重新挂回n，有子节点断开过时n的访问次数和胜率改为各子节点之和
+func (n *mctsNode) relink() {
+	detached := false
+	for _, child := range n.children {
+		if child.parent != n {
+			child.parent = n
+			child.relink()
+			detached = true
+		}
+	}
+	if !detached {
+		return
+	}
+	var visits int64
+	var wins float64
+	for _, child := range n.children {
+		v := child.visits.Load()
+		visits += v
+		wins += float64(v) - child.wins.Load() // 子节点的胜率以n的行棋方视角计，n的胜率以对方视角计
+	}
+	n.visits.Store(visits)
+	n.wins.Store(wins)
+}
+
+// 保留的根节点正是局面b且已展开时沿用它，根节点需要排除的着法从中去掉
+func (t *MCTS) reuse(b *AmazonBoard, color int) *mctsNode {
+	root := t.root
+	if root == nil || root.color != color || t.board != *b || !root.expanded || root.terminal {
+		return nil
+	}
+	if len(t.opts.Exclude) > 0 {
+		root.children = slices.DeleteFunc(root.children, func(c *mctsNode) bool { return slices.Contains(t.opts.Exclude, c.move) })
+		root.pending = slices.DeleteFunc(root.pending, func(m AmazonMove) bool { return slices.Contains(t.opts.Exclude, m) })
+		if len(root.children)+len(root.pending) == 0 {
+			return nil
+		}
+	}
+	return root
+}
+
+/*
+* 从局面b出发为color一方搜索，直到用完时间预算或模拟次数
+* 每次模拟依次经过选择、扩展、随机模拟（或提前评估）和回传四个阶段
+* 多线程时树并行的各线程共享根节点，根并行的各线程各自建树，最后合并统计
+* 保留的搜索树已走到局面b时从其根节点继续搜索，否则新建搜索树
+* 返回访问次数最多的着法；没有合法着法时返回false
+ */
+func (t *MCTS) Search(b *AmazonBoard, color int) (MCTSResult, bool) {
+	run := &mctsRun{start: time.Now()}
+	workers := make([]*mctsWorker, max(t.opts.Threads, 1))
+	for i := range workers {
+		workers[i] = &mctsWorker{t: t, rng: rand.New(rand.NewSource(t.rng.Int63()))}
+	}
+
+	root := t.reuse(b, color)
+	if root != nil {
+		run.reused = int(root.visits.Load())
+	} else {
+		root = workers[0].newRoot(b, color)
+	}
+	t.root, t.board = root, *b
+	if root.terminal {
+		return MCTSResult{}, false
+	}
+	roots := []*mctsNode{root}
+	if len(root.children)+len(root.pending) == 1 {
+		return t.result(roots, run), true
+	}
+
+	var wg sync.WaitGroup
+	for _, w := range workers[1:] {
+		r := root
+		if t.opts.Parallel == RootParallel {
+			r = w.newRoot(b, color)
+			roots = append(roots, r)
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w.run(r, b, run)
+		}()
+	}
+	workers[0].run(root, b, run)
+	wg.Wait()
+	return t.result(roots, run), true
+}
+
+// 创建并展开根节点
+func (w *mctsWorker) newRoot(b *AmazonBoard, color int) *mctsNode {
+	root := &mctsNode{color: color}
+	board := *b
+	w.expand(root, &board, w.t.opts.Step)
+	return root
+}
+
+// 单个线程的模拟循环，直到模拟次数或时间用完
+func (w *mctsWorker) run(root *mctsNode, b *AmazonBoard, run *mctsRun) {
+	t := w.t
+	var vl int64
+	if t.opts.Threads > 1 && t.opts.Parallel == TreeParallel {
+		vl = int64(t.opts.VirtualLoss)
+	}
+	limit := int64(t.opts.MaxIterations)
+	if limit <= 0 && t.opts.Budget <= 0 && t.opts.Stop == nil {
+		limit = minIterations
+	}
+	for i := 0; !run.stop.Load() && (t.opts.Stop == nil || !t.opts.Stop.Load()); i++ {
+		if i%checkInterval == 0 && t.opts.Budget > 0 && t.timeUp(root, int(run.iterations.Load()), time.Since(run.start)) {
+			run.stop.Store(true)
+			break
+		}
+		if limit > 0 && run.started.Add(1) > limit {
+			break
+		}
+
+		board := *b
+		node, step := root, t.opts.Step
+		// 选择：沿UCB值最大的子节点下行，直到未展开的叶节点或终局，经过的节点暂记虚拟失败
+		for next := t.selectChild(node); next != nil; next = t.selectChild(node) {
+			next.visits.Add(vl)
+			board.Move(next.move)
+			step++
+			node = next
+		}
+		// 扩展：延迟扩展时叶节点积累足够的模拟次数后才展开，展开后继续走入其第一个子节点
+		if w.tryExpand(node, &board, step) {
+			next := t.selectChild(node)
+			next.visits.Add(vl)
+			board.Move(next.move)
+			step++
+			node = next
+		}
+		value := w.simulate(&board, node.color, step)
+		// 回传：value为黑方胜率，每个节点按走入该节点的一方计入，同时撤销虚拟失败
+		for n := node; n != nil; n = n.parent {
+			if n.parent == nil {
+				n.visits.Add(1)
+			} else {
+				n.visits.Add(1 - vl)
+			}
+			if n.color == White {
+				n.wins.Add(value)
+			} else {
+				n.wins.Add(1 - value)
+			}
+		}
+		run.iterations.Add(1)
+	}
+}
+
+// 叶节点达到延迟扩展的阈值且没有其他线程正在展开时由本线程展开，展开后有着法时返回true
+func (w *mctsWorker) tryExpand(node *mctsNode, b *AmazonBoard, step int) bool {
+	node.mu.Lock()
+	if node.expanded || node.expanding || node.visits.Load()+1 < int64(w.t.opts.ExpandThreshold) {
+		node.mu.Unlock()
+		return false
+	}
+	node.expanding = true
+	node.mu.Unlock()
+	return w.expand(node, b, step)
+}
+
+// 生成节点的着法：需要静态排序时（估值筛选或渐进加宽）按一步评估从好到坏排序
+// 生成和排序不持锁，完成后一次性挂到节点上；有着法时返回true
+func (w *mctsWorker) expand(node *mctsNode, b *AmazonBoard, step int) bool {
+	t := w.t
+	w.buf = b.GenerateMoves(node.color, w.buf[:0])
+	moves := slices.Clone(w.buf)
+	if node.parent == nil && len(t.opts.Exclude) > 0 {
+		moves = slices.DeleteFunc(moves, func(m AmazonMove) bool { return slices.Contains(t.opts.Exclude, m) })
+	}
+	if len(moves) > 0 && (t.opts.PruneWidth > 0 || t.opts.WidenInterval > 0) {
+		scores := make([]float64, len(moves))
+		for i, m := range moves {
+			b.Move(m)
+			scores[i] = t.opts.Eval.Evaluate(b, step+1, node.color)
+			b.UndoMove(m)
+		}
+		sort.Sort(rootOrder{moves, scores})
+		if t.opts.PruneWidth > 0 && len(moves) > t.opts.PruneWidth {
+			moves = moves[:t.opts.PruneWidth]
+		}
+	}
+
+	node.mu.Lock()
+	defer node.mu.Unlock()
+	node.expanded, node.expanding = true, false
+	if len(moves) == 0 {
+		node.terminal = true
+		return false
+	}
+	node.pending = moves
+	if t.opts.WidenInterval == 0 {
+		t.open(node, len(moves))
+	} else {
+		t.widen(node)
+	}
+	return true
+}
+
+// 渐进加宽：按访问次数开放更多子节点，至少开放一个；调用时需持有node.mu
+func (t *MCTS) widen(node *mctsNode) {
+	if t.opts.WidenInterval == 0 || len(node.pending) == 0 {
+		return
+	}
+	allowed := max(t.opts.WidenInitial+t.opts.WidenStep*int(node.visits.Load()/int64(t.opts.WidenInterval)), 1)
+	if n := allowed - len(node.children); n > 0 {
+		t.open(node, n)
+	}
+}
+
+// 开放n个待开放的着法作为子节点；调用时需持有node.mu
+func (t *MCTS) open(node *mctsNode, n int) {
+	n = min(n, len(node.pending))
+	for _, m := range node.pending[:n] {
+		node.children = append(node.children, &mctsNode{move: m, color: 3 - node.color, parent: node})
+	}
+	node.pending = node.pending[n:]
+}
+
+// 按UCB1选择子节点，未访问过的子节点优先；节点尚未展开或为终局时返回nil
+func (t *MCTS) selectChild(node *mctsNode) *mctsNode {
+	node.mu.Lock()
+	defer node.mu.Unlock()
+	if !node.expanded || node.terminal {
+		return nil
+	}
+	t.widen(node)
+	logN := math.Log(float64(max(node.visits.Load(), 1)))
+	var best *mctsNode
+	bestValue := math.Inf(-1)
+	for _, c := range node.children {
+		visits := c.visits.Load()
+		if visits == 0 {
+			return c
+		}
+		v := c.wins.Load()/float64(visits) + t.opts.Exploration*math.Sqrt(logN/float64(visits))
+		if v > bestValue {
+			best, bestValue = c, v
+		}
+	}
+	return best
+}
+
+/*
+* 随机模拟，返回黑方胜率
+* 提前评估时随机走CutoffPlies步后用评估函数估计胜率，否则一直走到一方无子可动
+ */
+func (w *mctsWorker) simulate(b *AmazonBoard, color, step int) float64 {
+	t := w.t
+	for ply := 0; !t.opts.EarlyCutoff || ply < t.opts.CutoffPlies; ply++ {
+		w.buf = b.GenerateMoves(color, w.buf[:0])
+		if len(w.buf) == 0 {
+			if color == Black {
+				return 0
+			}
+			return 1
+		}
+		b.Move(w.buf[w.rng.Intn(len(w.buf))])
+		color = 3 - color
+		step++
+	}
+	if !b.HasMoves(color) {
+		if color == Black {
+			return 0
+		}
+		return 1
+	}
+	return 1 / (1 + math.Exp(-t.opts.Eval.Evaluate(b, step, Black)/t.opts.ValueScale))
+}
+
+/*
+* 动态分配时间
+* 关闭时用完预算即停止
+* 开启时：已用时间超过预算的1/4后，若按当前速度剩余时间内第二名也追不上访问最多的着法，则提前停止
+* 用完预算时若访问最多的着法的平均胜率低于其他着法，则继续搜索，最多到预算的maxTimeExtension倍
+* 根并行时只看调用线程自己的树
+ */
+func (t *MCTS) timeUp(root *mctsNode, iterations int, elapsed time.Duration) bool {
+	budget := t.opts.Budget
+	if !t.opts.TimeAlloc {
+		return elapsed >= budget
+	}
+	if elapsed >= budget*maxTimeExtension {
+		return true
+	}
+	children := root.snapshot()
+	first, second := topTwo(children)
+	if first == nil {
+		return elapsed >= budget
+	}
+	if elapsed >= budget {
+		best := bestByValue(children)
+		return best == nil || first.visits.Load() > 0 && first.mean() >= best.mean()
+	}
+	if elapsed < budget/4 || iterations == 0 {
+		return false
+	}
+	remaining := float64(iterations) * float64(budget-elapsed) / float64(elapsed)
+	var secondVisits int64
+	if second != nil {
+		secondVisits = second.visits.Load()
+	}
+	return float64(first.visits.Load()-secondVisits) > remaining
+}
+
+// 访问次数最多的两个子节点
+func topTwo(children []*mctsNode) (first, second *mctsNode) {
+	for _, c := range children {
+		switch {
+		case first == nil || c.visits.Load() > first.visits.Load():
+			first, second = c, first
+		case second == nil || c.visits.Load() > second.visits.Load():
+			second = c
+		}
+	}
+	return first, second
+}
+
+// 已访问子节点中平均胜率最高的
+func bestByValue(children []*mctsNode) *mctsNode {
+	var best *mctsNode
+	bestValue := -1.0
+	for _, c := range children {
+		if c.visits.Load() == 0 {
+			continue
+		}
+		if v := c.mean(); v > bestValue {
+			best, bestValue = c, v
+		}
+	}
+	return best
+}
+
+// 按着法合并各棵树根节点的子节点统计，选出访问次数最多的着法
+func (t *MCTS) result(roots []*mctsNode, run *mctsRun) MCTSResult {
+	res := MCTSResult{Visits: make(map[AmazonMove]int), Iterations: int(run.iterations.Load()), Reused: run.reused, Elapsed: time.Since(run.start)}
+	wins := make(map[AmazonMove]float64)
+	var order []AmazonMove
+	for _, root := range roots {
+		for _, c := range root.snapshot() {
+			if _, ok := wins[c.move]; !ok {
+				order = append(order, c.move)
+			}
+			res.Visits[c.move] += int(c.visits.Load())
+			wins[c.move] += c.wins.Load()
+		}
+	}
+	best := -1
+	for _, m := range order {
+		if res.Visits[m] > best {
+			res.Move, best = m, res.Visits[m]
+		}
+	}
+	if best > 0 {
+		res.Score = wins[res.Move] / float64(best)
+	}
+	for m, n := range res.Visits {
+		if n == 0 {
+			delete(res.Visits, m)
+		}
+	}
+	return res
+}
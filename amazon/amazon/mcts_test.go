@@ -0,0 +1,426 @@
+package amazon
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// 黑方可以把障碍射到(0,1)困死白方，走错则自己会被困死
+func trapPosition(t testing.TB) *AmazonBoard {
+	return parseBoard(t,
+		"W..B.XXXXX",
+		"XXXXXXXXXX",
+		"XXXXXXXXXX",
+		"XXXXXXXXXX",
+		"XXXXXXXXXX",
+		"XXXXXXXXXX",
+		"XXXXXXXXXX",
+		"XXXXXXXXXX",
+		"XXXXXXXXXX",
+		"XXXXXXXXXX",
+	)
+}
+
+// 一个4x5的封闭区域，双方各一个棋子，着法数适中，便于检查搜索树的形状
+func smallPosition(t testing.TB) *AmazonBoard {
+	return parseBoard(t,
+		"XXXXXXXXXX",
+		"XXXXXXXXXX",
+		"XXXXXXXXXX",
+		"XXX.....XX",
+		"XXX.B...XX",
+		"XXX...W.XX",
+		"XXX.....XX",
+		"XXXXXXXXXX",
+		"XXXXXXXXXX",
+		"XXXXXXXXXX",
+	)
+}
+
+// 遍历搜索树
+func walkTree(n *mctsNode, visit func(*mctsNode)) {
+	visit(n)
+	for _, c := range n.children {
+		walkTree(c, visit)
+	}
+}
+
+// 单独使用一个搜索线程的状态，用于直接测试随机模拟
+func newTestWorker(opts MCTSOptions) *mctsWorker {
+	t := NewMCTS(opts)
+	return &mctsWorker{t: t, rng: t.rng}
+}
+
+// 带有给定访问次数和累计胜率的子节点
+func statNode(parent *mctsNode, visits int64, wins float64) *mctsNode {
+	n := &mctsNode{parent: parent}
+	n.visits.Store(visits)
+	n.wins.Add(wins)
+	return n
+}
+
+func TestMCTSFindsWinningMove(t *testing.T) {
+	tests := []struct {
+		name string
+		opts MCTSOptions
+	}{
+		{"plain", MCTSOptions{}},
+		{"delayed expansion", MCTSOptions{ExpandThreshold: 5}},
+		{"pruning", MCTSOptions{PruneWidth: 3}},
+		{"widening", MCTSOptions{WidenInitial: 1, WidenStep: 2, WidenInterval: 20}},
+		{"early cutoff", MCTSOptions{EarlyCutoff: true, CutoffPlies: 2}},
+		{"time allocation", MCTSOptions{Budget: 200 * time.Millisecond, TimeAlloc: true}},
+		{"default", DefaultMCTSOptions},
+		{"tree parallel", MCTSOptions{Threads: 4, ExpandThreshold: 5}},
+		{"root parallel", MCTSOptions{Threads: 4, Parallel: RootParallel, ExpandThreshold: 5}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := tt.opts
+			opts.Seed = 1
+			if opts.Budget == 0 {
+				opts.MaxIterations = 2000
+			}
+			b := trapPosition(t)
+			res, ok := NewMCTS(opts).Search(b, Black)
+			if !ok {
+				t.Fatal("no move found")
+			}
+			b.Move(res.Move)
+			if win, _, ok := b.Solve(White, time.Time{}); !ok || win {
+				t.Fatalf("move %s (score %.3f, %d iterations) does not win", res.Move.Notation(), res.Score, res.Iterations)
+			}
+		})
+	}
+}
+
+func TestMCTSNoMoves(t *testing.T) {
+	b := trapPosition(t)
+	b[0][1] = Arrow
+	if _, ok := NewMCTS(MCTSOptions{MaxIterations: 10}).Search(b, White); ok {
+		t.Fatal("search succeeded without legal moves")
+	}
+}
+
+// 用时将尽时时间预算为0，搜索仍应很快返回一个着法
+func TestMCTSZeroBudget(t *testing.T) {
+	opts := DefaultMCTSOptions
+	opts.Seed = 1
+	done := make(chan MCTSResult)
+	go func() {
+		res, _ := NewMCTS(opts).Search(NewBoard(), Black)
+		done <- res
+	}()
+	select {
+	case res := <-done:
+		if res.Iterations != minIterations {
+			t.Fatalf("%d iterations, want %d", res.Iterations, minIterations)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("search with zero budget did not return")
+	}
+}
+
+func TestMCTSDelayedExpansion(t *testing.T) {
+	const threshold = 8
+	m := NewMCTS(MCTSOptions{ExpandThreshold: threshold, EarlyCutoff: true, MaxIterations: 3000, Seed: 1})
+	if _, ok := m.Search(smallPosition(t), Black); !ok {
+		t.Fatal("no move found")
+	}
+	expanded := 0
+	walkTree(m.root, func(n *mctsNode) {
+		if n != m.root && n.expanded {
+			expanded++
+			if n.visits.Load() < threshold {
+				t.Fatalf("node expanded after %d visits, want at least %d", n.visits.Load(), threshold)
+			}
+		}
+		if !n.expanded && n.visits.Load() >= threshold {
+			t.Fatalf("node with %d visits not expanded", n.visits.Load())
+		}
+	})
+	if expanded == 0 {
+		t.Fatal("no node beyond the root was expanded")
+	}
+}
+
+func TestMCTSPruneWidth(t *testing.T) {
+	const width = 7
+	m := NewMCTS(MCTSOptions{PruneWidth: width, EarlyCutoff: true, MaxIterations: 300, Seed: 1})
+	if _, ok := m.Search(smallPosition(t), Black); !ok {
+		t.Fatal("no move found")
+	}
+	walkTree(m.root, func(n *mctsNode) {
+		if got := len(n.children) + len(n.pending); got > width {
+			t.Fatalf("node keeps %d moves, want at most %d", got, width)
+		}
+	})
+	if len(m.root.children) != width {
+		t.Fatalf("root has %d children, want %d", len(m.root.children), width)
+	}
+	// 第一个子节点应是一步评估最好的着法
+	b := smallPosition(t)
+	best := math.Inf(-1)
+	for _, mv := range b.GenerateMoves(Black, nil) {
+		b.Move(mv)
+		best = math.Max(best, b.CalculateEvaluationValue(2, Black))
+		b.UndoMove(mv)
+	}
+	b.Move(m.root.children[0].move)
+	if got := b.CalculateEvaluationValue(2, Black); got != best {
+		t.Fatalf("first child scores %v, best move scores %v", got, best)
+	}
+}
+
+func TestMCTSProgressiveWidening(t *testing.T) {
+	const initial, step, interval = 2, 3, 40
+	m := NewMCTS(MCTSOptions{WidenInitial: initial, WidenStep: step, WidenInterval: interval, EarlyCutoff: true, MaxIterations: 300, Seed: 1})
+	if _, ok := m.Search(smallPosition(t), Black); !ok {
+		t.Fatal("no move found")
+	}
+	walkTree(m.root, func(n *mctsNode) {
+		if n.expanded && !n.terminal {
+			if limit := max(initial+step*int(n.visits.Load()/interval), 1); len(n.children) > limit {
+				t.Fatalf("node with %d visits opened %d children, want at most %d", n.visits.Load(), len(n.children), limit)
+			}
+		}
+	})
+	if got := len(m.root.children); got <= initial || len(m.root.pending) == 0 {
+		t.Fatalf("root opened %d children with %d pending, want gradual widening", got, len(m.root.pending))
+	}
+}
+
+func TestMCTSEarlyCutoff(t *testing.T) {
+	b := NewBoard()
+	w := newTestWorker(MCTSOptions{EarlyCutoff: true, CutoffPlies: 0, Seed: 1})
+	want := 1 / (1 + math.Exp(-b.CalculateEvaluationValue(1, Black)/DefaultValueScale))
+	board := *b
+	if got := w.simulate(&board, Black, 1); got != want || board != *b {
+		t.Fatalf("cutoff at 0 plies: value %v, want static %v", got, want)
+	}
+
+	w = newTestWorker(MCTSOptions{EarlyCutoff: true, CutoffPlies: 3, Seed: 1})
+	board = *b
+	w.simulate(&board, Black, 1)
+	if got := 92 - board.CountEmpty(); got != 3 {
+		t.Fatalf("cutoff at 3 plies filled %d squares, want 3", got)
+	}
+
+	// 关闭提前评估时模拟到终局，结果只能是胜或负
+	w = newTestWorker(MCTSOptions{Seed: 1})
+	board = *b
+	if got := w.simulate(&board, Black, 1); got != 0 && got != 1 {
+		t.Fatalf("full playout value %v, want 0 or 1", got)
+	}
+	if board.HasMoves(Black) && board.HasMoves(White) {
+		t.Fatal("full playout stopped before the game ended")
+	}
+}
+
+func TestMCTSTimeAllocation(t *testing.T) {
+	const budget = time.Second
+	// 根节点的两个子节点，a访问多、b平均胜率高
+	root := &mctsNode{color: Black}
+	a := statNode(root, 900, 450)
+	b := statNode(root, 100, 80)
+	root.children = []*mctsNode{a, b}
+
+	fixed := NewMCTS(MCTSOptions{Budget: budget})
+	dynamic := NewMCTS(MCTSOptions{Budget: budget, TimeAlloc: true})
+	tests := []struct {
+		name    string
+		m       *MCTS
+		iters   int
+		elapsed time.Duration
+		want    bool
+	}{
+		{"fixed before budget", fixed, 1000, budget / 2, false},
+		{"fixed at budget", fixed, 1000, budget, true},
+		{"too early to stop", dynamic, 1000, budget / 8, false},
+		{"lead cannot be overtaken", dynamic, 1000, budget * 3 / 4, true},
+		{"lead can still be overtaken", dynamic, 1000, budget / 2, false},
+		{"unstable best move extends", dynamic, 1000, budget, false},
+		{"extension limit", dynamic, 1000, budget * maxTimeExtension, true},
+	}
+	for _, tt := range tests {
+		if got := tt.m.timeUp(root, tt.iters, tt.elapsed); got != tt.want {
+			t.Errorf("%s: timeUp = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	// 访问最多的着法也是平均胜率最高的着法时按时结束
+	b.wins.Add(10 - b.wins.Load())
+	if !dynamic.timeUp(root, 1000, budget) {
+		t.Error("stable best move: timeUp = false at budget")
+	}
+}
+
+func TestMCTSTreeParallel(t *testing.T) {
+	const iterations = 4000
+	m := NewMCTS(MCTSOptions{Threads: 4, ExpandThreshold: 4, EarlyCutoff: true, MaxIterations: iterations, Seed: 1})
+	res, ok := m.Search(smallPosition(t), Black)
+	if !ok {
+		t.Fatal("no move found")
+	}
+	if res.Iterations != iterations {
+		t.Fatalf("%d iterations, want %d", res.Iterations, iterations)
+	}
+	// 所有虚拟失败都已撤销：每个节点的访问次数等于经过它的模拟次数，即其子节点访问次数之和加上停在本节点的模拟
+	walkTree(m.root, func(n *mctsNode) {
+		var sum int64
+		for _, c := range n.children {
+			sum += c.visits.Load()
+		}
+		if visits := n.visits.Load(); visits < sum || n.expanded && !n.terminal && n != m.root && visits > sum+int64(m.opts.ExpandThreshold) {
+			t.Fatalf("node with %d visits has children totalling %d", visits, sum)
+		}
+		if w := n.wins.Load(); w < 0 || w > float64(n.visits.Load()) {
+			t.Fatalf("node with %d visits has %v wins", n.visits.Load(), w)
+		}
+	})
+	if got := m.root.visits.Load(); got != iterations {
+		t.Fatalf("root visits %d, want %d", got, iterations)
+	}
+	total := 0
+	for _, n := range res.Visits {
+		total += n
+	}
+	if total != iterations {
+		t.Fatalf("root children visits total %d, want %d", total, iterations)
+	}
+}
+
+func TestMCTSRootParallel(t *testing.T) {
+	const threads, iterations = 4, 2000
+	m := NewMCTS(MCTSOptions{Threads: threads, Parallel: RootParallel, EarlyCutoff: true, MaxIterations: iterations, Seed: 1})
+	res, ok := m.Search(smallPosition(t), Black)
+	if !ok {
+		t.Fatal("no move found")
+	}
+	// 合并后的访问次数覆盖所有线程的模拟
+	total := 0
+	for _, n := range res.Visits {
+		total += n
+	}
+	if total != iterations || res.Iterations != iterations {
+		t.Fatalf("merged visits %d over %d iterations, want %d", total, res.Iterations, iterations)
+	}
+	best := 0
+	for _, n := range res.Visits {
+		best = max(best, n)
+	}
+	if res.Visits[res.Move] != best {
+		t.Fatalf("move %s has %d visits, most visited has %d", res.Move.Notation(), res.Visits[res.Move], best)
+	}
+}
+
+func TestMCTSTreeReuse(t *testing.T) {
+	b := smallPosition(t)
+	m := NewMCTS(MCTSOptions{EarlyCutoff: true, MaxIterations: 3000, Seed: 1})
+	res, ok := m.Search(b, Black)
+	if !ok {
+		t.Fatal("no move found")
+	}
+	// 沿本方着法和访问最多的应着下行到孙节点
+	var child *mctsNode
+	for _, c := range m.root.children {
+		if c.move == res.Move {
+			child = c
+		}
+	}
+	reply, _ := topTwo(child.children)
+	if reply == nil {
+		t.Fatal("best move has no expanded replies")
+	}
+	want := reply.visits.Load()
+	if !m.Advance(res.Move) || !m.Advance(reply.move) {
+		t.Fatal("advance failed")
+	}
+	b.Move(res.Move)
+	b.Move(reply.move)
+	next, ok := m.Search(b, Black)
+	if !ok {
+		t.Fatal("no move found after advancing")
+	}
+	if next.Reused != int(want) || m.root != reply || m.root.parent != nil {
+		t.Fatalf("reused %d visits, want %d from the grandchild", next.Reused, want)
+	}
+	if got := m.root.visits.Load(); got != want+int64(next.Iterations) {
+		t.Fatalf("root visits %d, want %d reused plus %d new", got, want, next.Iterations)
+	}
+
+	// 树中没有的着法丢弃整棵树，之后从新树开始
+	if m.Advance(AmazonMove{}) || m.root != nil {
+		t.Fatal("advance with an unknown move kept the tree")
+	}
+	if res, _ := m.Search(b, Black); res.Reused != 0 {
+		t.Fatalf("fresh search reused %d visits", res.Reused)
+	}
+}
+
+func TestMCTSRollback(t *testing.T) {
+	b := smallPosition(t)
+	m := NewMCTS(MCTSOptions{EarlyCutoff: true, MaxIterations: 3000, Seed: 1})
+	res, ok := m.Search(b, Black)
+	if !ok {
+		t.Fatal("no move found")
+	}
+	root, visits := m.root, m.root.visits.Load()
+
+	// 走出本方着法并在对方回合继续思考，随后平台判为错误
+	cp := m.Checkpoint()
+	if !m.Advance(res.Move) {
+		t.Fatal("advance failed")
+	}
+	after := *b
+	after.Move(res.Move)
+	if _, ok := m.Search(&after, White); !ok {
+		t.Fatal("no reply found after advancing")
+	}
+	pondered := m.root.visits.Load()
+	m.Restore(cp)
+	if m.root != root || m.board != *b {
+		t.Fatal("restore did not return to the checkpoint")
+	}
+	// 对方回合的模拟保留在子节点上，原根节点的统计按子节点重新计算
+	var sum int64
+	var wins float64
+	for _, c := range m.root.children {
+		if c.parent != root {
+			t.Fatalf("child %s is detached from the restored root", c.move.Notation())
+		}
+		sum += c.visits.Load()
+		wins += float64(c.visits.Load()) - c.wins.Load()
+		if c.move == res.Move && c.visits.Load() != pondered {
+			t.Fatalf("pondered child has %d visits, want %d", c.visits.Load(), pondered)
+		}
+	}
+	if restored := root.visits.Load(); restored != sum || restored <= visits || !almostEqual(root.wins.Load(), wins) {
+		t.Fatalf("restored root has %d visits and %.2f wins, want %d visits (more than %d) and %.2f wins",
+			restored, root.wins.Load(), sum, visits, wins)
+	}
+	visits = sum
+
+	// 排除被拒绝的着法后重新搜索，沿用原根节点的访问次数
+	m.SetOptions(MCTSOptions{EarlyCutoff: true, MaxIterations: 3000, Seed: 1, Exclude: []AmazonMove{res.Move}})
+	next, ok := m.Search(b, Black)
+	if !ok {
+		t.Fatal("no move found after restoring")
+	}
+	if next.Reused != int(visits) || next.Move == res.Move {
+		t.Fatalf("reused %d visits with move %s, want %d visits and a move other than %s",
+			next.Reused, next.Move.Notation(), visits, res.Move.Notation())
+	}
+
+	// 树中没有的着法丢弃了整棵树，同样可以撤销
+	cp = m.Checkpoint()
+	root = m.root
+	if m.Advance(AmazonMove{}) {
+		t.Fatal("advance with an unknown move succeeded")
+	}
+	m.Restore(cp)
+	if m.root != root || m.board != *b {
+		t.Fatal("restore after an unknown move did not return to the checkpoint")
+	}
+}
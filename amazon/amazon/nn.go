@@ -0,0 +1,179 @@
+// 实现纯Go的多层感知机估值网络，在CPU上推理，权重从JSON文件加载，可替代手工评估函数。
+package amazon
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"slices"
+	"sync"
+)
+
+/*
+* 网络输入特征平面，均以行棋方视角给出，每个平面10x10，按x*10+y展开后依次拼接
+* 距离平面的取值为1/距离，不可达的格子为0
+ */
+const (
+	PlaneOwn        = iota // 本方棋子
+	PlaneEnemy             // 对方棋子
+	PlaneArrow             // 障碍
+	PlaneOwnQueen          // 本方queen距离
+	PlaneEnemyQueen        // 对方queen距离
+	PlaneOwnKing           // 本方king距离
+	PlaneEnemyKing         // 对方king距离
+	NetPlanes              // 平面数
+)
+
+// 网络输入的长度
+const NetInputSize = NetPlanes * 100
+
+// 激活函数名称
+const (
+	ActLinear = ""
+	ActReLU   = "relu"
+	ActTanh   = "tanh"
+)
+
+// Layer 为一个全连接层：out = act(Weights * in + Bias)
+type Layer struct {
+	Weights    [][]float64 `json:"weights"`    // 输出数 x 输入数
+	Bias       []float64   `json:"bias"`       // 输出数
+	Activation string      `json:"activation"` // relu、tanh或为空（线性）
+}
+
+// Network 为估值网络，最后一层只有一个输出，乘以Scale后作为评估值
+type Network struct {
+	Layers []Layer `json:"layers"`
+	Scale  float64 `json:"scale"` // 输出到评估分值的缩放，为0时取1
+
+	scratch sync.Pool // 每次推理使用的中间结果缓冲区
+}
+
+// 检查各层尺寸是否首尾相接
+func (n *Network) Validate() error {
+	if len(n.Layers) == 0 {
+		return errors.New("network: no layers")
+	}
+	in := NetInputSize
+	for i, l := range n.Layers {
+		if len(l.Weights) == 0 || len(l.Weights) != len(l.Bias) {
+			return fmt.Errorf("network: layer %d has %d weight rows and %d biases", i, len(l.Weights), len(l.Bias))
+		}
+		for _, row := range l.Weights {
+			if len(row) != in {
+				return fmt.Errorf("network: layer %d expects %d inputs, got row of %d", i, in, len(row))
+			}
+		}
+		switch l.Activation {
+		case ActLinear, ActReLU, ActTanh:
+		default:
+			return fmt.Errorf("network: layer %d has unknown activation %q", i, l.Activation)
+		}
+		in = len(l.Weights)
+	}
+	if in != 1 {
+		return fmt.Errorf("network: last layer has %d outputs, want 1", in)
+	}
+	return nil
+}
+
+// 从JSON文件加载网络
+func LoadNetwork(path string) (*Network, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	n := &Network{}
+	if err := json.Unmarshal(data, n); err != nil {
+		return nil, fmt.Errorf("network %s: %w", path, err)
+	}
+	if err := n.Validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return n, nil
+}
+
+// 将网络保存为JSON文件
+func (n *Network) Save(path string) error {
+	data, err := json.Marshal(n)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
+
+// 以color一方视角计算网络输出
+func (n *Network) Evaluate(b *AmazonBoard, turn, color int) float64 {
+	buf, _ := n.scratch.Get().(*[]float64)
+	if buf == nil {
+		size := NetInputSize
+		for _, l := range n.Layers {
+			size += len(l.Weights)
+		}
+		s := make([]float64, 0, size)
+		buf = &s
+	}
+	// 输入和各层输出依次存放在同一个缓冲区中
+	in := b.Features(color, (*buf)[:0])
+	for _, l := range n.Layers {
+		out := in[len(in) : len(in)+len(l.Weights)]
+		for i, row := range l.Weights {
+			v := l.Bias[i]
+			for j, w := range row {
+				v += w * in[j]
+			}
+			switch l.Activation {
+			case ActReLU:
+				v = math.Max(v, 0)
+			case ActTanh:
+				v = math.Tanh(v)
+			}
+			out[i] = v
+		}
+		in = out
+	}
+	value := in[0]
+	n.scratch.Put(buf)
+
+	if n.Scale != 0 {
+		value *= n.Scale
+	}
+	return value
+}
+
+// 以color一方视角生成网络输入特征，追加到dst后返回
+func (b *AmazonBoard) Features(color int, dst []float64) []float64 {
+	p := b.ToBitboard()
+	own, enemy := p.Pieces(color), p.Pieces(3-color)
+	ownQueen, enemyQueen := p.Distances(color, QueenReach), p.Distances(3-color, QueenReach)
+	ownKing, enemyKing := p.Distances(color, KingReach), p.Distances(3-color, KingReach)
+
+	start := len(dst)
+	dst = slices.Grow(dst, NetInputSize)[:start+NetInputSize]
+	f := dst[start:]
+	clear(f)
+	for sq := 0; sq < 100; sq++ {
+		switch {
+		case own.Has(sq):
+			f[PlaneOwn*100+sq] = 1
+		case enemy.Has(sq):
+			f[PlaneEnemy*100+sq] = 1
+		case p.Arrows.Has(sq):
+			f[PlaneArrow*100+sq] = 1
+		}
+		f[PlaneOwnQueen*100+sq] = inverseDistance(ownQueen[sq])
+		f[PlaneEnemyQueen*100+sq] = inverseDistance(enemyQueen[sq])
+		f[PlaneOwnKing*100+sq] = inverseDistance(ownKing[sq])
+		f[PlaneEnemyKing*100+sq] = inverseDistance(enemyKing[sq])
+	}
+	return dst
+}
+
+func inverseDistance(d int) float64 {
+	if d == Unreachable {
+		return 0
+	}
+	return 1 / float64(d)
+}
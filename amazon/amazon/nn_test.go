@@ -0,0 +1,194 @@
+package amazon
+
+import (
+	"math"
+	"math/rand"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// 一行输入权重：plane平面上的每个格子取weight，其余为0
+func planeRow(plane int, weight float64) []float64 {
+	row := make([]float64, NetInputSize)
+	for sq := 0; sq < 100; sq++ {
+		row[plane*100+sq] = weight
+	}
+	return row
+}
+
+// 随机权重的小网络
+func randomNetwork(r *rand.Rand, hidden int) *Network {
+	layer := func(out, in int, act string) Layer {
+		l := Layer{Weights: make([][]float64, out), Bias: make([]float64, out), Activation: act}
+		for i := range l.Weights {
+			l.Weights[i] = make([]float64, in)
+			for j := range l.Weights[i] {
+				l.Weights[i][j] = r.NormFloat64() * 0.1
+			}
+			l.Bias[i] = r.NormFloat64() * 0.1
+		}
+		return l
+	}
+	return &Network{Layers: []Layer{
+		layer(hidden, NetInputSize, ActReLU),
+		layer(hidden, hidden, ActTanh),
+		layer(1, hidden, ActLinear),
+	}, Scale: 100}
+}
+
+func TestNetworkKnownOutput(t *testing.T) {
+	// 隐层：本方棋子数；障碍数减0.5后截断到0。输出：2*棋子数+5*隐层二+0.5，再乘以10
+	n := &Network{
+		Layers: []Layer{
+			{Weights: [][]float64{planeRow(PlaneOwn, 1), planeRow(PlaneArrow, 1)}, Bias: []float64{0, -0.5}, Activation: ActReLU},
+			{Weights: [][]float64{{2, 5}}, Bias: []float64{0.5}},
+		},
+		Scale: 10,
+	}
+	if err := n.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	b := NewBoard()
+	if got := n.Evaluate(b, 1, Black); got != 85 {
+		t.Errorf("opening = %v, want 85", got)
+	}
+	b.Move(AmazonMove{From: Position{9, 3}, To: Position{5, 3}, Put: Position{5, 7}})
+	b.Move(AmazonMove{From: Position{0, 3}, To: Position{4, 3}, Put: Position{4, 7}})
+	if got := n.Evaluate(b, 3, White); got != 85+10*5*1.5 {
+		t.Errorf("two arrows = %v, want %v", got, 85+10*5*1.5)
+	}
+}
+
+func TestNetworkValidate(t *testing.T) {
+	valid := func() *Network { return randomNetwork(rand.New(rand.NewSource(1)), 4) }
+	tests := []struct {
+		name   string
+		modify func(n *Network)
+		err    string // 空串表示合法
+	}{
+		{"valid", func(n *Network) {}, ""},
+		{"no layers", func(n *Network) { n.Layers = nil }, "no layers"},
+		{"missing bias", func(n *Network) { n.Layers[1].Bias = n.Layers[1].Bias[1:] }, "weight rows"},
+		{"short input row", func(n *Network) { n.Layers[0].Weights[2] = n.Layers[0].Weights[2][1:] }, "expects 700 inputs"},
+		{"layers do not chain", func(n *Network) { n.Layers[1].Weights[0] = append(n.Layers[1].Weights[0], 0) }, "expects 4 inputs"},
+		{"unknown activation", func(n *Network) { n.Layers[0].Activation = "sigmoid" }, "unknown activation"},
+		{"two outputs", func(n *Network) {
+			n.Layers[2].Weights = append(n.Layers[2].Weights, n.Layers[2].Weights[0])
+			n.Layers[2].Bias = append(n.Layers[2].Bias, 0)
+		}, "want 1"},
+	}
+	for _, tt := range tests {
+		n := valid()
+		tt.modify(n)
+		err := n.Validate()
+		switch {
+		case tt.err == "" && err != nil:
+			t.Errorf("%s: Validate() = %v", tt.name, err)
+		case tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)):
+			t.Errorf("%s: Validate() = %v, want error containing %q", tt.name, err, tt.err)
+		}
+	}
+}
+
+func TestNetworkSaveLoad(t *testing.T) {
+	n := randomNetwork(rand.New(rand.NewSource(2)), 4)
+	path := filepath.Join(t.TempDir(), "net.json")
+	if err := n.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadNetwork(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := NewBoard()
+	if got, want := loaded.Evaluate(b, 1, Black), n.Evaluate(b, 1, Black); got != want {
+		t.Errorf("loaded network = %v, want %v", got, want)
+	}
+
+	// 尺寸不符的网络在加载时被拒绝
+	n.Layers[0].Bias = nil
+	if err := n.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadNetwork(path); err == nil {
+		t.Error("LoadNetwork accepted a network with mismatched shapes")
+	}
+}
+
+func TestFeaturesLayout(t *testing.T) {
+	r := rand.New(rand.NewSource(3))
+	for i := 0; i < 50; i++ {
+		b, color := randomPosition(r, r.Intn(80))
+		prefix := []float64{-1, -2}
+		f := b.Features(color, prefix)
+		if len(f) != len(prefix)+NetInputSize || f[0] != -1 || f[1] != -2 {
+			t.Fatalf("position %d: Features did not append to dst", i)
+		}
+		f = f[len(prefix):]
+		queen := [2]AmazonBoard{}
+		king := [2]AmazonBoard{}
+		queen[0], queen[1] = b.CalculateQueenMoves()
+		king[0], king[1] = b.CalculateKingMoves()
+		own, enemy := color-1, 2-color // 距离图按黑、白存放
+		for x := 0; x < 10; x++ {
+			for y := 0; y < 10; y++ {
+				sq := x*10 + y
+				want := map[int]float64{
+					PlaneOwn:        boolFeature(b[x][y] == color),
+					PlaneEnemy:      boolFeature(b[x][y] == 3-color),
+					PlaneArrow:      boolFeature(b[x][y] == Arrow),
+					PlaneOwnQueen:   inverseDistance(queen[own][x][y]),
+					PlaneEnemyQueen: inverseDistance(queen[enemy][x][y]),
+					PlaneOwnKing:    inverseDistance(king[own][x][y]),
+					PlaneEnemyKing:  inverseDistance(king[enemy][x][y]),
+				}
+				for plane, w := range want {
+					if got := f[plane*100+sq]; got != w {
+						t.Fatalf("position %d: plane %d at (%d,%d) = %v, want %v", i, plane, x, y, got, w)
+					}
+				}
+			}
+		}
+	}
+}
+
+func boolFeature(v bool) float64 {
+	if v {
+		return 1
+	}
+	return 0
+}
+
+func TestNetworkConcurrentEvaluate(t *testing.T) {
+	r := rand.New(rand.NewSource(4))
+	n := randomNetwork(r, 16)
+	boards := make([]*AmazonBoard, 32)
+	want := make([]float64, len(boards))
+	for i := range boards {
+		boards[i], _ = randomPosition(r, r.Intn(60))
+		want[i] = n.Evaluate(boards[i], 10, Black)
+	}
+	// 多个搜索线程共用同一个网络，各自的中间结果互不干扰
+	var wg sync.WaitGroup
+	errs := make(chan string, 8)
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for k := 0; k < 50; k++ {
+				i := (g*7 + k) % len(boards)
+				if got := n.Evaluate(boards[i], 10, Black); math.Abs(got-want[i]) > 1e-9 {
+					errs <- "concurrent evaluation differs from serial"
+					return
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+	close(errs)
+	for e := range errs {
+		t.Fatal(e)
+	}
+}
@@ -0,0 +1,192 @@
+// 实现Alpha-Beta搜索的着法排序：置换表着法、杀手着法、历史启发与静态预评分。
+package amazon
+
+import (
+	"math"
+	"slices"
+	"sort"
+)
+
+// Ordering 为着法排序使用的启发，可按位组合
+type Ordering uint8
+
+const (
+	OrderTT      Ordering = 1 << iota // 置换表中的最佳着法排在最前
+	OrderKillers                      // 同一层最近引起截断的两个杀手着法紧随其后
+	OrderHistory                      // 其余着法按历史得分排序，着法引起截断时其走子和射箭各累加剩余深度的平方
+	OrderStatic                       // 历史得分之外再加上静态预评分，见 neighborhood.score
+
+	OrderAll = OrderTT | OrderKillers | OrderHistory | OrderStatic
+)
+
+const maxHistory = 1 << 20 // 历史得分的上限，避免溢出并让静态预评分在得分相近时仍起作用
+
+// 历史得分表，按格子编号(x*10+y)分别记录棋子的起点与终点、障碍的起点（即棋子落点）与落点
+// 两张表共80KB，着法的历史得分为两者之和；同一走子或同一射箭出现在大量着法中，拆开统计也更快积累
+type historyTable struct {
+	move  [100][100]int32
+	arrow [100][100]int32
+}
+
+// 着法m的历史得分
+func (h *historyTable) score(m AmazonMove) int32 {
+	to := m.To.X*10 + m.To.Y
+	return h.move[m.From.X*10+m.From.Y][to] + h.arrow[to][m.Put.X*10+m.Put.Y]
+}
+
+// 着法m引起截断时分别增加其走子和射箭的得分，各自不超过maxHistory
+func (h *historyTable) add(m AmazonMove, bonus int32) {
+	to := m.To.X*10 + m.To.Y
+	move, arrow := &h.move[m.From.X*10+m.From.Y][to], &h.arrow[to][m.Put.X*10+m.Put.Y]
+	*move = min(*move+bonus, maxHistory)
+	*arrow = min(*arrow+bonus, maxHistory)
+}
+
+// 局面中各格周围的空格数和棋子数，同一局面的大量着法共用一份，使每个着法的预评分只需常数时间
+type neighborhood struct {
+	empty  [100]int8 // 周围的空格数
+	pieces [100]int8 // 周围的对方棋子数减去本方棋子数
+}
+
+// 以color一方视角统计各格的周围情况
+func (b *AmazonBoard) neighborhood(color int, n *neighborhood) {
+	for x := 0; x < 10; x++ {
+		for y := 0; y < 10; y++ {
+			var empty, pieces int8
+			for _, d := range dir {
+				if nx, ny := x+d[0], y+d[1]; b.legal(nx, ny) {
+					switch b[nx][ny] {
+					case Empty:
+						empty++
+					case color:
+						pieces--
+					case 3 - color:
+						pieces++
+					}
+				}
+			}
+			n.empty[x*10+y], n.pieces[x*10+y] = empty, pieces
+		}
+	}
+}
+
+/*
+* 着法的静态预评分，只看着法附近的格子，远比完整评估便宜：
+* 棋子落点与起点周围的空格数之差，即该棋子局部灵活度的变化；
+* 障碍每紧邻一个对方棋子加2分、每紧邻一个本方棋子减2分，贴近对方的障碍更可能限制其行动
+* 按着法前的统计计算，再修正着法本身带来的变化：起点空出、落点被本方棋子占据、障碍占去空格
+ */
+func (n *neighborhood) score(m AmazonMove) int {
+	score := int(n.empty[m.To.X*10+m.To.Y]) - int(n.empty[m.From.X*10+m.From.Y]) + 2*int(n.pieces[m.Put.X*10+m.Put.Y])
+	if adjacent(m.From, m.To) {
+		score++
+	}
+	if adjacent(m.Put, m.To) {
+		score -= 3
+	}
+	if adjacent(m.Put, m.From) {
+		score += 2
+	}
+	return score
+}
+
+// 两个格子是否相邻（含斜向）
+func adjacent(a, b Position) bool {
+	dx, dy := a.X-b.X, a.Y-b.Y
+	return (dx != 0 || dy != 0) && dx >= -1 && dx <= 1 && dy >= -1 && dy <= 1
+}
+
+/*
+* 对color一方在第ply层的着法排序
+* 置换表着法最先，其次为本层的杀手着法，其余按历史得分加静态预评分从高到低排列
+* 其余着法不立即整体排序，而是返回与moves对齐的得分，由 nextMove 在搜索时逐个挑出，
+* 截断通常发生在前几个着法上，可省去大部分排序
+* 被opts.NoOrdering关闭的启发不参与排序；历史启发和静态预评分都关闭时返回nil
+ */
+func (s *searcher) orderMoves(moves []AmazonMove, ply, color int, ttMove AmazonMove, hasTT bool) []int32 {
+	off := s.opts.NoOrdering
+	n := 0 // 已固定在最前面的着法数
+	if hasTT && off&OrderTT == 0 {
+		if i := slices.Index(moves, ttMove); i >= 0 {
+			moves[0], moves[i] = moves[i], moves[0]
+			n = 1
+		}
+	}
+	if off&OrderKillers == 0 {
+		for _, k := range s.killers[ply] {
+			if i := slices.Index(moves[n:], k); i >= 0 {
+				moves[n], moves[n+i] = moves[n+i], moves[n]
+				n++
+			}
+		}
+	}
+	if off&(OrderHistory|OrderStatic) == OrderHistory|OrderStatic {
+		return nil
+	}
+
+	scores := slices.Grow(s.scoreBuf[ply][:0], len(moves))[:len(moves)]
+	s.scoreBuf[ply] = scores
+	var near neighborhood
+	if off&OrderStatic == 0 {
+		s.board.neighborhood(color, &near)
+	}
+	for i, m := range moves {
+		var v int32
+		switch {
+		case i < n:
+			v = math.MaxInt32 // 已固定的着法保持在前
+		default:
+			if off&OrderHistory == 0 {
+				v = s.history.score(m)
+			}
+			if off&OrderStatic == 0 {
+				v += int32(near.score(m))
+			}
+		}
+		scores[i] = v
+	}
+	return scores
+}
+
+const lazyPicks = 8 // 逐个挑选的着法数，之后对剩余着法整体排序
+
+// 将moves[i:]中得分最高的着法换到第i位；挑满lazyPicks个后对剩余着法一次排好
+func nextMove(moves []AmazonMove, scores []int32, i int) {
+	switch {
+	case scores == nil || i > lazyPicks:
+	case i == lazyPicks:
+		sort.Stable(moveOrder{moves[i:], scores[i:]})
+	default:
+		best := i
+		for j := i + 1; j < len(moves); j++ {
+			if scores[j] > scores[best] {
+				best = j
+			}
+		}
+		moves[i], moves[best] = moves[best], moves[i]
+		scores[i], scores[best] = scores[best], scores[i]
+	}
+}
+
+// 着法m在第ply层引起beta截断：记为本层的杀手着法，并按剩余深度的平方增加其历史得分
+func (s *searcher) cutoff(m AmazonMove, depth, ply int) {
+	if s.opts.NoOrdering&OrderKillers == 0 && s.killers[ply][0] != m {
+		s.killers[ply][1], s.killers[ply][0] = s.killers[ply][0], m
+	}
+	if s.opts.NoOrdering&OrderHistory == 0 {
+		s.history.add(m, int32(depth*depth))
+	}
+}
+
+// 着法与排序得分的联合排序，得分高者在前
+type moveOrder struct {
+	moves  []AmazonMove
+	scores []int32
+}
+
+func (o moveOrder) Len() int           { return len(o.moves) }
+func (o moveOrder) Less(i, j int) bool { return o.scores[i] > o.scores[j] }
+func (o moveOrder) Swap(i, j int) {
+	o.moves[i], o.moves[j] = o.moves[j], o.moves[i]
+	o.scores[i], o.scores[j] = o.scores[j], o.scores[i]
+}
@@ -0,0 +1,128 @@
+// 定义可调的评估参数，支持从JSON配置文件加载，便于不同版本的引擎使用不同参数。
+package amazon
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// Weight 为一个要素在某阶段内的权重，随步数线性变化：Base + Slope*步数
+type Weight struct {
+	Base  float64 `json:"base"`
+	Slope float64 `json:"slope"`
+}
+
+// 第turn步时的权重
+func (w Weight) At(turn int) float64 {
+	return w.Base + w.Slope*float64(turn)
+}
+
+// EvalPhase 为一个评估阶段，步数小于Until时使用本阶段的权重，Until为0表示直到终局
+type EvalPhase struct {
+	Until          int    `json:"until"`
+	QueenTerritory Weight `json:"queen_territory"`
+	KingTerritory  Weight `json:"king_territory"`
+	P1             Weight `json:"p1"`
+	P2             Weight `json:"p2"`
+	Mobility       Weight `json:"mobility"`
+}
+
+// EvalParams 为评估函数的全部参数，阶段按Until从小到大排列，最后一个阶段的Until为0
+type EvalParams struct {
+	Phases []EvalPhase `json:"phases"`
+}
+
+/*
+* 默认参数，即原先写死在评估函数中的权重
+* 前17步：k1=2*(32+t/2) k2=k3=32-1.8*t/2 k4=2*(32-2*t/2) k5=32-1.8*t/2
+* 之后只看queen领土，k1=5
+* 灵活度原为比值 黑/白，现为归一化差值 (黑-白)/(黑+白)，均势附近前者偏离1的幅度约为后者的两倍，
+* 因此k5取原先0.5*(32-1.8*t/2)的两倍，使同样的灵活度差距对评估值的影响不变
+ */
+var DefaultEvalParams = EvalParams{
+	Phases: []EvalPhase{
+		{
+			Until:          17,
+			QueenTerritory: Weight{Base: 64, Slope: 1},
+			KingTerritory:  Weight{Base: 32, Slope: -0.9},
+			P1:             Weight{Base: 32, Slope: -0.9},
+			P2:             Weight{Base: 64, Slope: -2},
+			Mobility:       Weight{Base: 32, Slope: -0.9},
+		},
+		{
+			Until:          0,
+			QueenTerritory: Weight{Base: 5},
+		},
+	},
+}
+
+// 第turn步所处的阶段
+func (p *EvalParams) Phase(turn int) *EvalPhase {
+	for i := range p.Phases {
+		if p.Phases[i].Until == 0 || turn < p.Phases[i].Until {
+			return &p.Phases[i]
+		}
+	}
+	return &p.Phases[len(p.Phases)-1]
+}
+
+// 第turn步时各要素的权重
+func (p *EvalParams) Weights(turn int) EvalTerms {
+	ph := p.Phase(turn)
+	return EvalTerms{
+		QueenTerritory: ph.QueenTerritory.At(turn),
+		KingTerritory:  ph.KingTerritory.At(turn),
+		P1:             ph.P1.At(turn),
+		P2:             ph.P2.At(turn),
+		Mobility:       ph.Mobility.At(turn),
+	}
+}
+
+// 检查阶段划分是否有效
+func (p *EvalParams) Validate() error {
+	if len(p.Phases) == 0 {
+		return errors.New("eval params: no phases")
+	}
+	prev := 0
+	for i, ph := range p.Phases {
+		last := i == len(p.Phases)-1
+		switch {
+		case last && ph.Until != 0:
+			return fmt.Errorf("eval params: last phase must have until 0, got %d", ph.Until)
+		case !last && ph.Until <= prev:
+			return fmt.Errorf("eval params: phase %d until %d is not increasing", i, ph.Until)
+		}
+		prev = ph.Until
+	}
+	return nil
+}
+
+// 从JSON文件加载评估参数，未知的字段视为拼写错误而拒绝，缺少的权重取0
+func LoadEvalParams(path string) (EvalParams, error) {
+	var p EvalParams
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return p, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&p); err != nil {
+		return p, fmt.Errorf("eval params %s: %w", path, err)
+	}
+	if err := p.Validate(); err != nil {
+		return p, fmt.Errorf("%s: %w", path, err)
+	}
+	return p, nil
+}
+
+// 将评估参数保存为JSON文件
+func (p *EvalParams) Save(path string) error {
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
@@ -0,0 +1,85 @@
+package amazon
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestEvalParamsPhase(t *testing.T) {
+	p := EvalParams{Phases: []EvalPhase{{Until: 10}, {Until: 30}, {Until: 0}}}
+	tests := []struct {
+		turn, phase int
+	}{
+		{1, 0},
+		{9, 0},
+		{10, 1}, // Until本身属于下一阶段
+		{29, 1},
+		{30, 2},
+		{200, 2},
+	}
+	for _, tt := range tests {
+		if got := p.Phase(tt.turn); got != &p.Phases[tt.phase] {
+			t.Errorf("Phase(%d) = phase until %d, want phase %d", tt.turn, got.Until, tt.phase)
+		}
+	}
+	// 默认参数第17步起只看queen领土
+	if w := DefaultEvalParams.Weights(16); w.Mobility == 0 {
+		t.Error("default weights at turn 16 ignore mobility")
+	}
+	if w := DefaultEvalParams.Weights(17); w != (EvalTerms{QueenTerritory: 5}) {
+		t.Errorf("default weights at turn 17 = %+v", w)
+	}
+}
+
+func TestLoadEvalParams(t *testing.T) {
+	tests := []struct {
+		name string
+		json string
+		err  string // 空串表示合法
+	}{
+		{"valid", `{"phases": [{"until": 20, "mobility": {"base": 3, "slope": -0.1}}, {"until": 0, "p1": {"base": 1}}]}`, ""},
+		{"missing weights", `{"phases": [{"until": 0}]}`, ""},
+		{"missing phases", `{}`, "no phases"},
+		{"empty phases", `{"phases": []}`, "no phases"},
+		{"last phase until", `{"phases": [{"until": 20}]}`, "last phase must have until 0"},
+		{"missing until", `{"phases": [{"p1": {"base": 1}}, {"until": 0}]}`, "not increasing"},
+		{"decreasing until", `{"phases": [{"until": 20}, {"until": 10}, {"until": 0}]}`, "not increasing"},
+		{"unknown field", `{"phases": [{"until": 0, "mobilty": {"base": 1}}]}`, "unknown field"},
+		{"wrong type", `{"phases": [{"until": "20"}]}`, "cannot unmarshal"},
+		{"malformed", `{"phases": [`, "unexpected EOF"},
+	}
+	dir := t.TempDir()
+	for _, tt := range tests {
+		path := filepath.Join(dir, strings.ReplaceAll(tt.name, " ", "_")+".json")
+		if err := os.WriteFile(path, []byte(tt.json), 0644); err != nil {
+			t.Fatal(err)
+		}
+		_, err := LoadEvalParams(path)
+		switch {
+		case tt.err == "" && err != nil:
+			t.Errorf("%s: LoadEvalParams() = %v", tt.name, err)
+		case tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)):
+			t.Errorf("%s: LoadEvalParams() = %v, want error containing %q", tt.name, err, tt.err)
+		}
+	}
+	if _, err := LoadEvalParams(filepath.Join(dir, "missing.json")); !os.IsNotExist(err) {
+		t.Errorf("missing file: %v", err)
+	}
+}
+
+func TestEvalParamsSaveLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "eval.json")
+	if err := DefaultEvalParams.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadEvalParams(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(loaded, DefaultEvalParams) {
+		t.Errorf("loaded %+v, want %+v", loaded, DefaultEvalParams)
+	}
+}
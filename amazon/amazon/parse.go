@@ -0,0 +1,126 @@
+// 解析平台发送的着法字符串，并按亚马逊棋规则检查其合法性。
+package amazon
+
+import (
+	"errors"
+	"fmt"
+)
+
+// 着法检查可能返回的错误类型，可通过 errors.Is 判断具体原因
+var (
+	ErrMoveFormat   = errors.New("malformed move string")
+	ErrOutOfBoard   = errors.New("coordinate out of board")
+	ErrNotOwnPiece  = errors.New("source square is not an amazon of the side to move")
+	ErrPathBlocked  = errors.New("amazon path is not a clear queen line")
+	ErrArrowBlocked = errors.New("arrow path is not a clear queen line")
+)
+
+// MoveError 描述一个非法着法及其原因
+type MoveError struct {
+	Move string // 原始着法字符串
+	Err  error  // 具体原因
+}
+
+func (e *MoveError) Error() string {
+	return fmt.Sprintf("invalid move %q: %v", e.Move, e.Err)
+}
+
+func (e *MoveError) Unwrap() error {
+	return e.Err
+}
+
+/*
+* 解析SAU平台的六字母着法字符串，例如"DAEBFC"
+* 每两个字母构成一个坐标，第一个字母为横轴(列)，第二个字母为纵轴(行)
+* 三个坐标依次为起点、终点和障碍位置
+* 只检查格式与坐标范围，不检查棋规
+ */
+func ParseMove(s string) (AmazonMove, error) {
+	if len(s) != 6 {
+		return AmazonMove{}, &MoveError{Move: s, Err: ErrMoveFormat}
+	}
+	var pos [3]Position
+	for i := range pos {
+		col, row := s[2*i], s[2*i+1]
+		if col < 'A' || col > 'Z' || row < 'A' || row > 'Z' {
+			return AmazonMove{}, &MoveError{Move: s, Err: ErrMoveFormat}
+		}
+		pos[i] = Position{X: int(row - 'A'), Y: int(col - 'A')}
+		if pos[i].X >= 10 || pos[i].Y >= 10 {
+			return AmazonMove{}, &MoveError{Move: s, Err: ErrOutOfBoard}
+		}
+	}
+	return AmazonMove{From: pos[0], To: pos[1], Put: pos[2]}, nil
+}
+
+// 将着法转换为SAU平台的六字母字符串，与 ParseMove 互逆
+func (m AmazonMove) Notation() string {
+	return fmt.Sprintf("%c%c%c%c%c%c", m.From.Y+'A', m.From.X+'A', m.To.Y+'A', m.To.X+'A', m.Put.Y+'A', m.Put.X+'A')
+}
+
+/*
+* 检查着法对指定颜色一方是否合法
+* 起点必须是该方的棋子
+* 棋子沿直线或斜线移动，途经及落点均为空
+* 障碍从落点沿直线或斜线射出，途经及落点均为空（棋子离开的起点视为空）
+ */
+func (b *AmazonBoard) CheckMove(m AmazonMove, color int) error {
+	s := m.Notation()
+	for _, p := range []Position{m.From, m.To, m.Put} {
+		if !b.legal(p.X, p.Y) {
+			return &MoveError{Move: s, Err: ErrOutOfBoard}
+		}
+	}
+	if b[m.From.X][m.From.Y] != color {
+		return &MoveError{Move: s, Err: ErrNotOwnPiece}
+	}
+	if !b.clearLine(m.From, m.To, m.From) {
+		return &MoveError{Move: s, Err: ErrPathBlocked}
+	}
+	if !b.clearLine(m.To, m.Put, m.From) {
+		return &MoveError{Move: s, Err: ErrArrowBlocked}
+	}
+	return nil
+}
+
+// 解析着法字符串并检查其对指定颜色一方是否合法
+func (b *AmazonBoard) ParseLegalMove(s string, color int) (AmazonMove, error) {
+	m, err := ParseMove(s)
+	if err != nil {
+		return AmazonMove{}, err
+	}
+	if err := b.CheckMove(m, color); err != nil {
+		return AmazonMove{}, err
+	}
+	return m, nil
+}
+
+// 检查from到to是否为一条畅通的直线或斜线（不含from，含to），vacated位置视为空
+func (b *AmazonBoard) clearLine(from, to, vacated Position) bool {
+	dx, dy := to.X-from.X, to.Y-from.Y
+	if dx == 0 && dy == 0 {
+		return false
+	}
+	if dx != 0 && dy != 0 && dx != dy && dx != -dy {
+		return false
+	}
+	sx, sy := sign(dx), sign(dy)
+	for x, y := from.X+sx, from.Y+sy; ; x, y = x+sx, y+sy {
+		if b[x][y] != Empty && (x != vacated.X || y != vacated.Y) {
+			return false
+		}
+		if x == to.X && y == to.Y {
+			return true
+		}
+	}
+}
+
+func sign(v int) int {
+	switch {
+	case v > 0:
+		return 1
+	case v < 0:
+		return -1
+	}
+	return 0
+}
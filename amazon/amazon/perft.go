@@ -0,0 +1,27 @@
+// 实现perft计数，用于校验步法生成的正确性。
+package amazon
+
+// 从当前局面由color一方先走，统计走depth层后的叶子节点数
+// 每个子节点在棋盘副本上展开，计数结果不依赖 UndoMove
+func (b *AmazonBoard) Perft(color, depth int) int64 {
+	if depth <= 0 {
+		return 1
+	}
+	bufs := make([][]AmazonMove, depth)
+	return b.perft(color, depth, bufs)
+}
+
+func (b *AmazonBoard) perft(color, depth int, bufs [][]AmazonMove) int64 {
+	moves := b.GenerateMoves(color, bufs[depth-1][:0])
+	bufs[depth-1] = moves
+	if depth == 1 {
+		return int64(len(moves)) // 最后一层只需计数，无需执行着法
+	}
+	var nodes int64
+	for _, m := range moves {
+		child := *b
+		child.Move(m)
+		nodes += child.perft(3-color, depth-1, bufs)
+	}
+	return nodes
+}
@@ -0,0 +1,106 @@
+package amazon
+
+import "testing"
+
+// 由10行字符串构造棋盘："."空位，"B"黑方，"W"白方，"X"障碍
+func parseBoard(t testing.TB, rows ...string) *AmazonBoard {
+	t.Helper()
+	if len(rows) != 10 {
+		t.Fatalf("board needs 10 rows, got %d", len(rows))
+	}
+	b := &AmazonBoard{}
+	for x, row := range rows {
+		if len(row) != 10 {
+			t.Fatalf("row %d needs 10 columns, got %q", x, row)
+		}
+		for y, c := range row {
+			switch c {
+			case '.':
+				b[x][y] = Empty
+			case 'B':
+				b[x][y] = Black
+			case 'W':
+				b[x][y] = White
+			case 'X':
+				b[x][y] = Arrow
+			default:
+				t.Fatalf("unknown square %q at (%d,%d)", c, x, y)
+			}
+		}
+	}
+	return b
+}
+
+func TestPerft(t *testing.T) {
+	// 左上角2x2口袋里的黑棋，右下角只有一个空位的白棋
+	pocket := parseBoard(t,
+		"B.XXXXXXXX",
+		"..XXXXXXXX",
+		"XXXXXXXXXX",
+		"XXXXXXXXXX",
+		"XXXXXXXXXX",
+		"XXXXXXXXXX",
+		"XXXXXXXXXX",
+		"XXXXXXXXXX",
+		"XXXXXXXXXX",
+		"XXXXXXXX.W",
+	)
+	// 一字走廊：棋子向右走后障碍须穿过离开的起点才能射到最左端
+	corridor := parseBoard(t,
+		".B..XXXXXX",
+		"XXXXXXXXXX",
+		"XXXXXXXXXX",
+		"XXXXXXXXXX",
+		"XXXXXXXXXX",
+		"XXXXXXXXXX",
+		"XXXXXXXXXX",
+		"XXXXXXXXXX",
+		"XXXXXXXXXX",
+		"XXXXXXXXXX",
+	)
+	// 斜线走廊，同样需要穿过起点射出障碍
+	diagonal := parseBoard(t,
+		".XXXXXXXXX",
+		"XBXXXXXXXX",
+		"XX.XXXXXXX",
+		"XXX.XXXXXX",
+		"XXXXXXXXXX",
+		"XXXXXXXXXX",
+		"XXXXXXXXXX",
+		"XXXXXXXXXX",
+		"XXXXXXXXXX",
+		"XXXXXXXXXX",
+	)
+
+	tests := []struct {
+		name  string
+		board *AmazonBoard
+		color int
+		depth int
+		want  int64
+	}{
+		{"opening black depth 1", NewBoard(), Black, 1, 2176},
+		{"opening white depth 1", NewBoard(), White, 1, 2176},
+		{"opening depth 2", NewBoard(), Black, 2, 4307152},
+		{"pocket depth 1", pocket, Black, 1, 9},
+		{"pocket white depth 1", pocket, White, 1, 1},
+		{"pocket depth 2", pocket, Black, 2, 9},
+		{"pocket depth 3", pocket, Black, 3, 36},
+		{"pocket depth 4", pocket, Black, 4, 0},
+		{"corridor depth 1", corridor, Black, 1, 9},
+		{"corridor no white", corridor, Black, 2, 0},
+		{"diagonal depth 1", diagonal, Black, 1, 9},
+		{"depth 0", NewBoard(), Black, 0, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := *tt.board
+			if got := tt.board.Perft(tt.color, tt.depth); got != tt.want {
+				t.Errorf("Perft(%d, %d) = %d, want %d", tt.color, tt.depth, got, tt.want)
+			}
+			if *tt.board != before {
+				t.Errorf("Perft modified the board")
+			}
+		})
+	}
+}
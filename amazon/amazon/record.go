@@ -4,7 +4,12 @@ package amazon
 import (
 	"bufio"
 	"fmt"
+	"io"
 	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -17,12 +22,14 @@ type Record struct {
 	ArrowY int
 }
 
-var (
-	recordSlice []Record
-)
+// GameRecord 保存一局棋的全部移动记录，每个对局会话各自持有一份
+type GameRecord struct {
+	Dir     string // 棋谱保存目录，为空时使用"../chess/"
+	records []Record
+}
 
-func AddRecord(fromX, fromY, toX, toY, arrowX, arrowY int) {
-	recordSlice = append(recordSlice, Record{
+func (r *GameRecord) AddRecord(fromX, fromY, toX, toY, arrowX, arrowY int) {
+	r.records = append(r.records, Record{
 		FromX:  rune(fromX),
 		FromY:  fromY,
 		ToX:    rune(toX),
@@ -32,16 +39,47 @@ func AddRecord(fromX, fromY, toX, toY, arrowX, arrowY int) {
 	})
 }
 
-func Save() {
-	desktopPath := "../chess/"
+// 按棋谱坐标格式记录一次移动
+func (r *GameRecord) AddMove(m AmazonMove) {
+	r.AddRecord(m.From.Y+'a', 10-m.From.X, m.To.Y+'a', 10-m.To.X, m.Put.Y+'a', 10-m.Put.X)
+}
+
+// 撤销最近一条移动记录
+func (r *GameRecord) Undo() {
+	if len(r.records) > 0 {
+		r.records = r.records[:len(r.records)-1]
+	}
+}
+
+// 棋谱中的胜负标记，黑方先手
+func resultText(winner int) string {
+	switch winner {
+	case Black:
+		return "先手胜"
+	case White:
+		return "后手胜"
+	}
+	return "先/后手胜"
+}
+
+// 保存棋谱，winner为获胜方颜色，未知时传入Empty
+func (r *GameRecord) Save(winner int) {
+	desktopPath := r.Dir
+	if desktopPath == "" {
+		desktopPath = "../chess/"
+	}
 	if err := os.MkdirAll(desktopPath, 0755); err != nil {
 		fmt.Printf("Error creating directory: %v\n", err)
 		return
 	}
 
 	// 创建文件名
-	filename := fmt.Sprintf(desktopPath+"先手队 vs 后手队-先/后手胜-%v.txt",
-		time.Now().Format("2006年01月02日 15时04分"))
+	result := resultText(winner)
+	if winner == Empty {
+		result = "未分胜负" // 文件名中不能出现"/"
+	}
+	filename := filepath.Join(desktopPath, fmt.Sprintf("先手队 vs 后手队-%s-%v.txt",
+		result, time.Now().Format("2006年01月02日 15时04分")))
 	file, err := os.Create(filename)
 	if err != nil {
 		fmt.Printf("Error creating file: %v\n", err)
@@ -50,14 +88,14 @@ func Save() {
 	defer file.Close()
 
 	writer := bufio.NewWriter(file)
-	_, err = writer.WriteString("#[AM][先手参赛队][后手参赛队][先/后手胜]" +
+	_, err = writer.WriteString("#[AM][先手参赛队][后手参赛队][" + resultText(winner) + "]" +
 		time.Now().Format("2006.01.02 15:04") + ";\r\n")
 	if err != nil {
 		fmt.Printf("Error writing to file: %v\n", err)
 		return
 	}
 
-	for i, record := range recordSlice {
+	for i, record := range r.records {
 		if i%2 == 0 {
 			_, err = writer.WriteString(fmt.Sprintf("%v ", i/2+1))
 			if err != nil {
@@ -90,5 +128,65 @@ func Save() {
 		fmt.Printf("Error flushing to file: %v\n", err)
 		return
 	}
-	recordSlice = recordSlice[:0]
+	r.records = r.records[:0]
+}
+
+// 棋谱中的一步，例如"a4a7(b7)"
+var recordMove = regexp.MustCompile(`([a-j])(10|[1-9])([a-j])(10|[1-9])\(([a-j])(10|[1-9])\)`)
+
+/*
+* 读取由 GameRecord.Save 保存的棋谱
+* 返回全部着法和获胜方，棋谱未标明胜负时winner为Empty
+* 着法按顺序在初始局面上复盘检查，遇到非法着法时返回错误
+ */
+func ParseRecord(r io.Reader) (moves []AmazonMove, winner int, err error) {
+	sc := bufio.NewScanner(r)
+	board := NewBoard()
+	color := Black
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if strings.HasPrefix(line, "#") {
+			switch {
+			case strings.Contains(line, "["+resultText(Black)+"]"):
+				winner = Black
+			case strings.Contains(line, "["+resultText(White)+"]"):
+				winner = White
+			}
+			continue
+		}
+		for _, f := range recordMove.FindAllStringSubmatch(line, -1) {
+			m := AmazonMove{
+				From: recordPosition(f[1], f[2]),
+				To:   recordPosition(f[3], f[4]),
+				Put:  recordPosition(f[5], f[6]),
+			}
+			if err := board.CheckMove(m, color); err != nil {
+				return nil, winner, fmt.Errorf("move %d %s: %w", len(moves)+1, f[0], err)
+			}
+			board.Move(m)
+			moves = append(moves, m)
+			color = 3 - color
+		}
+	}
+	return moves, winner, sc.Err()
+}
+
+// 读取棋谱文件
+func LoadRecord(path string) ([]AmazonMove, int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, Empty, err
+	}
+	defer f.Close()
+	moves, winner, err := ParseRecord(f)
+	if err != nil {
+		return nil, Empty, fmt.Errorf("%s: %w", path, err)
+	}
+	return moves, winner, nil
+}
+
+// 棋谱坐标转换为棋盘坐标：字母为列，数字为从下往上数的行
+func recordPosition(col, row string) Position {
+	n, _ := strconv.Atoi(row)
+	return Position{X: 10 - n, Y: int(col[0] - 'a')}
 }
@@ -0,0 +1,251 @@
+// 划分封闭区域并计算残局填充步数，所有区域归属确定后按最优顺序填充。
+package amazon
+
+// RegionKind 表示区域的归属
+type RegionKind int
+
+const (
+	RegionDead      RegionKind = iota // 没有空格或没有棋子，双方都无法在其中行棋
+	RegionBlack                       // 只有黑方棋子
+	RegionWhite                       // 只有白方棋子
+	RegionContested                   // 双方棋子都在其中，尚未确定归属
+)
+
+// 空格数不超过该值的单方区域用穷举计算精确步数，否则用贪心填充得到下界
+const exactRegionSize = 12
+
+// Region 为一个被障碍和棋盘边界封闭的连通区域（按八邻域连通）
+type Region struct {
+	Squares Bitboard   // 区域内的空格
+	Black   Bitboard   // 区域内的黑方棋子
+	White   Bitboard   // 区域内的白方棋子
+	Kind    RegionKind // 区域归属
+	Moves   int        // 占有方在该区域内最多可走的步数，仅对单方区域有效
+	Exact   bool       // Moves是否为精确值，否则为下界
+	first   AmazonMove // 达到Moves的填充序列的第一步
+}
+
+// Partition 为一个局面的全部区域，划分一次即可在同一局面上多次判断填充和求解
+type Partition []Region
+
+/*
+* 区域划分
+* 非障碍格子（空格和棋子）按八邻域划分连通块，棋子的走法和障碍都不会越出所在区域
+* 只含一方棋子的区域归该方所有，并计算该方在其中的步数
+ */
+func (b *AmazonBoard) Regions() Partition {
+	p := b.ToBitboard()
+	return p.Regions()
+}
+
+func (p *AmazonBitboard) Regions() Partition {
+	return p.regions(newFillCache())
+}
+
+// 单方区域步数的缓存，键为只保留该区域的局面，可在多次区域划分之间共享
+type fillCache struct {
+	regions map[AmazonBitboard]fillCount // 区域的步数
+	exact   map[AmazonBitboard]int       // 穷举过程中各局面的步数
+}
+
+type fillCount struct {
+	moves int
+	exact bool
+	first AmazonMove
+}
+
+func newFillCache() *fillCache {
+	return &fillCache{regions: make(map[AmazonBitboard]fillCount), exact: make(map[AmazonBitboard]int)}
+}
+
+func (p *AmazonBitboard) regions(cache *fillCache) []Region {
+	var regions []Region
+	open := p.Arrows.Not()
+	for rest := open; !rest.IsZero(); {
+		area := SquareBit(rest.PopLSB())
+		for {
+			grown := area.Or(KingReach(area, open))
+			if grown == area {
+				break
+			}
+			area = grown
+		}
+		rest = rest.AndNot(area)
+
+		r := Region{
+			Squares: area.AndNot(p.Occupied),
+			Black:   area.And(p.Black),
+			White:   area.And(p.White),
+		}
+		switch {
+		case r.Squares.IsZero() || r.Black.IsZero() && r.White.IsZero():
+			r.Kind = RegionDead
+			r.Exact = true
+		case r.White.IsZero():
+			r.Kind = RegionBlack
+		case r.Black.IsZero():
+			r.Kind = RegionWhite
+		default:
+			r.Kind = RegionContested
+		}
+		if r.Kind == RegionBlack || r.Kind == RegionWhite {
+			r.Moves, r.Exact, r.first = r.countMoves(cache)
+		}
+		regions = append(regions, r)
+	}
+	return regions
+}
+
+// 区域的占有方
+func (r *Region) Owner() int {
+	switch r.Kind {
+	case RegionBlack:
+		return Black
+	case RegionWhite:
+		return White
+	}
+	return Empty
+}
+
+// 只保留本区域的局面，区域外全部视为障碍
+func (r *Region) board() AmazonBitboard {
+	area := r.Squares.Or(r.Black).Or(r.White)
+	p := AmazonBitboard{Black: r.Black, White: r.White, Arrows: area.Not()}
+	p.Occupied = p.Black.Or(p.White).Or(p.Arrows)
+	return p
+}
+
+// 计算占有方在区域内的步数，返回步数、是否精确和对应填充序列的第一步
+func (r *Region) countMoves(cache *fillCache) (int, bool, AmazonMove) {
+	p := r.board()
+	if c, ok := cache.regions[p]; ok {
+		return c.moves, c.exact, c.first
+	}
+	color := r.Owner()
+	c := fillCount{exact: r.Squares.Count() <= exactRegionSize}
+	if c.exact {
+		c.moves, c.first = p.bestFillMove(color, cache.exact)
+	} else {
+		c.moves, c.first = p.greedyFillMoves(color)
+	}
+	cache.regions[p] = c
+	return c.moves, c.exact, c.first
+}
+
+// 穷举计算color一方最多能走的步数及最优序列的第一步；每步消耗一个空格，达到空格数即为上界，可提前结束
+func (p *AmazonBitboard) bestFillMove(color int, memo map[AmazonBitboard]int) (int, AmazonMove) {
+	limit := p.Empty().Count()
+	best := 0
+	var first AmazonMove
+	for _, m := range p.GenerateMoves(color, nil) {
+		p.Move(m)
+		n := p.maxFillMoves(color, memo) + 1
+		p.UndoMove(m)
+		if n > best {
+			best, first = n, m
+			if best == limit {
+				break
+			}
+		}
+	}
+	return best, first
+}
+
+// 带缓存的穷举步数
+func (p *AmazonBitboard) maxFillMoves(color int, memo map[AmazonBitboard]int) int {
+	if n, ok := memo[*p]; ok {
+		return n
+	}
+	n, _ := p.bestFillMove(color, memo)
+	memo[*p] = n
+	return n
+}
+
+/*
+* 贪心填充：每步选择之后仍与本方棋子连通的空格最多的着法，直到无子可动
+* 得到的是一条实际可走的序列，因此步数是精确步数的下界
+ */
+func (p *AmazonBitboard) greedyFillMoves(color int) (int, AmazonMove) {
+	q := *p
+	n := 0
+	var first AmazonMove
+	var buf []AmazonMove
+	for {
+		buf = q.GenerateMoves(color, buf[:0])
+		if len(buf) == 0 {
+			return n, first
+		}
+		best, bestArea := buf[0], -1
+		for _, m := range buf {
+			q.Move(m)
+			if area := q.reachableArea(color); area > bestArea {
+				best, bestArea = m, area
+			}
+			q.UndoMove(m)
+		}
+		if n == 0 {
+			first = best
+		}
+		q.Move(best)
+		n++
+	}
+}
+
+// 与color一方棋子八邻域连通的空格数
+func (p *AmazonBitboard) reachableArea(color int) int {
+	empty := p.Empty()
+	area := p.Pieces(color)
+	for {
+		grown := area.Or(KingReach(area, empty))
+		if grown == area {
+			return area.And(empty).Count()
+		}
+		area = grown
+	}
+}
+
+// 统计双方在各自区域内的步数之和，contested表示是否还有争夺中的区域
+func (b *AmazonBoard) FillingCounts() (black, white int, contested bool) {
+	return b.Regions().FillingCounts()
+}
+
+func (rs Partition) FillingCounts() (black, white int, contested bool) {
+	for _, r := range rs {
+		switch r.Kind {
+		case RegionBlack:
+			black += r.Moves
+		case RegionWhite:
+			white += r.Moves
+		case RegionContested:
+			contested = true
+		}
+	}
+	return black, white, contested
+}
+
+/*
+* 残局填充
+* 所有区域的归属都已确定时，双方只能在各自区域内行棋，胜负取决于双方的步数
+* 优先在已算出精确步数的区域内沿最优序列走棋，每走一步本方总步数只减少一
+* 仍有争夺区域或本方已无子可动时返回false
+ */
+func (b *AmazonBoard) FillingMove(color int) (AmazonMove, bool) {
+	return b.Regions().FillingMove(color)
+}
+
+func (rs Partition) FillingMove(color int) (AmazonMove, bool) {
+	var move AmazonMove
+	found, exact := false, false
+	for _, r := range rs {
+		if r.Kind == RegionContested {
+			return AmazonMove{}, false
+		}
+		if r.Owner() != color || r.Moves == 0 {
+			continue
+		}
+		if !found || r.Exact && !exact {
+			move, found, exact = r.first, true, r.Exact
+		}
+	}
+	return move, found
+}
@@ -0,0 +1,112 @@
+package amazon
+
+import "testing"
+
+// 含有格子(x,y)的区域
+func regionAt(t *testing.T, rs Partition, x, y int) Region {
+	t.Helper()
+	for _, r := range rs {
+		if r.Squares.Or(r.Black).Or(r.White).Has(x*10 + y) {
+			return r
+		}
+	}
+	t.Fatalf("no region contains (%d,%d)", x, y)
+	return Region{}
+}
+
+func TestRegions(t *testing.T) {
+	b := parseBoard(t,
+		"B..X.W.XXX", // 黑方两格口袋；白方两格口袋，第二步须让障碍穿过离开的起点
+		"XXXXXXXXXX",
+		"X.XBXXXXXX", // 没有棋子的空格；没有空格的黑棋
+		"XXXXXXXXXX",
+		"..........", // 十九个空格的白方区域，超过穷举的规模
+		"W.........",
+		"XXXXXXXXXX",
+		"B..XXXXXXX", // 双方争夺的区域
+		"...XXXXXXX",
+		"..WXXXXXXX",
+	)
+	rs := b.Regions()
+	if len(rs) != 6 {
+		t.Fatalf("got %d regions, want 6", len(rs))
+	}
+	tests := []struct {
+		name  string
+		x, y  int
+		kind  RegionKind
+		moves int
+		exact bool
+	}{
+		{"black pocket", 0, 0, RegionBlack, 2, true},
+		{"white pocket", 0, 5, RegionWhite, 2, true},
+		{"empty square", 2, 1, RegionDead, 0, true},
+		{"walled in piece", 2, 3, RegionDead, 0, true},
+		{"contested", 7, 0, RegionContested, 0, false},
+	}
+	for _, tt := range tests {
+		r := regionAt(t, rs, tt.x, tt.y)
+		if r.Kind != tt.kind || r.Moves != tt.moves || r.Exact != tt.exact {
+			t.Errorf("%s: kind %d moves %d exact %v, want kind %d moves %d exact %v",
+				tt.name, r.Kind, r.Moves, r.Exact, tt.kind, tt.moves, tt.exact)
+		}
+	}
+
+	// 大区域只用贪心填充，得到不超过空格数的下界
+	large := regionAt(t, rs, 5, 0)
+	if large.Kind != RegionWhite || large.Exact {
+		t.Errorf("large region: kind %d exact %v, want a white lower bound", large.Kind, large.Exact)
+	}
+	if n := large.Squares.Count(); large.Moves <= 0 || large.Moves > n {
+		t.Errorf("large region: %d moves for %d empty squares", large.Moves, n)
+	}
+
+	black, white, contested := rs.FillingCounts()
+	if black != 2 || white != 2+large.Moves || !contested {
+		t.Errorf("FillingCounts = %d, %d, %v, want 2, %d, true", black, white, contested, 2+large.Moves)
+	}
+	if _, ok := rs.FillingMove(Black); ok {
+		t.Error("FillingMove with a contested region")
+	}
+}
+
+func TestFillingMoveOptimal(t *testing.T) {
+	// 黑棋居中的一字走廊，先向右走时障碍须射回左端才能走满三步；白棋在2x2角落
+	b := parseBoard(t,
+		"X.B..XXXXX",
+		"XXXXXXXXXX",
+		"XXXXXXXXXX",
+		"XXXXXXXXXX",
+		"XXXXXXXXXX",
+		"XXXXXXXXXX",
+		"XXXXXXXXXX",
+		"XXXXXXXXXX",
+		"XXXXXXXX..",
+		"XXXXXXXXW.",
+	)
+	black, white, contested := b.FillingCounts()
+	if black != 3 || white != 3 || contested {
+		t.Fatalf("FillingCounts = %d, %d, %v, want 3, 3, false", black, white, contested)
+	}
+	// 双方轮流按填充走棋，每步本方步数恰好减少一，直到走满
+	color := Black
+	for played := 0; ; played++ {
+		m, ok := b.FillingMove(color)
+		if !ok {
+			if played != 6 {
+				t.Fatalf("filling stopped after %d moves, want 6", played)
+			}
+			break
+		}
+		if err := b.CheckMove(m, color); err != nil {
+			t.Fatalf("filling move %d: %v", played, err)
+		}
+		b.Move(m)
+		nb, nw, _ := b.FillingCounts()
+		if color == Black && (nb != black-1 || nw != white) || color == White && (nw != white-1 || nb != black) {
+			t.Fatalf("after filling move %d counts %d, %d, were %d, %d", played, nb, nw, black, white)
+		}
+		black, white = nb, nw
+		color = 3 - color
+	}
+}
@@ -0,0 +1,289 @@
+// 实现迭代加深的Alpha-Beta(负极大值)搜索，按截止时间返回最后一轮完整迭代的最佳着法。
+package amazon
+
+import (
+	"math"
+	"slices"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	MateScore = 1e6 // 必胜/必败局面的分值，减去到达的层数以优先选择更快的胜利
+	maxPly    = 64  // 最大搜索层数
+)
+
+// SearchOptions 配置一次搜索
+type SearchOptions struct {
+	Step     int                // 当前步数，用于评估函数的阶段权重
+	MaxDepth int                // 最大迭代深度，0表示直到时间用完
+	Deadline time.Time          // 截止时间，零值表示不限时间（此时必须设置MaxDepth或Stop）
+	Exclude  []AmazonMove       // 根节点需要排除的着法
+	TT       *TransTable        // 置换表，可跨多次搜索复用；为nil时新建
+	Threads  int                // 搜索线程数，0或1表示单线程
+	Eval     Evaluator          // 评估器，为nil时使用默认参数的手工评估函数
+	Info     func(SearchResult) // 每完成一轮迭代时回调，可为nil
+	Stop     *atomic.Bool       // 外部停止信号，置位后搜索尽快结束，可为nil
+
+	NoOrdering Ordering // 关闭的着法排序启发，零值表示全部启用，用于比较各项启发的效果
+}
+
+// SearchResult 为一次搜索（或一轮迭代）的结果
+type SearchResult struct {
+	Move    AmazonMove    // 最佳着法
+	Score   float64       // 以本方视角的分值
+	Depth   int           // 已完成的迭代深度
+	Nodes   int64         // 搜索的节点数
+	Elapsed time.Duration // 已用时间
+}
+
+type searcher struct {
+	board   *HashedBoard
+	tt      *TransTable
+	opts    SearchOptions
+	start   time.Time
+	nodes   int64
+	stopped bool
+	stop    *atomic.Bool         // 多线程搜索时由主线程置位，通知辅助线程结束
+	moveBuf [maxPly][]AmazonMove // 每层复用的着法缓冲区
+
+	killers  [maxPly][2]AmazonMove // 每层最近引起截断的着法
+	history  *historyTable         // 历史启发得分，每个线程各一份
+	scoreBuf [maxPly][]int32       // 每层复用的排序得分缓冲区
+}
+
+/*
+* 迭代加深搜索
+* 从深度1开始逐层加深，每轮按上一轮的得分重新排序根节点着法
+* 到达截止时间时中止当前迭代，返回最后一轮完整迭代的最佳着法
+* 若第一轮迭代都未完成，则返回该轮已搜索部分中的最佳着法
+* Threads大于1时按Lazy SMP并行：辅助线程在同一局面上以错开的深度和根着法顺序搜索，
+* 只通过共享的置换表影响主线程，结果以主线程为准
+* 没有合法着法时返回false
+ */
+func (b *AmazonBoard) Search(color int, opts SearchOptions) (SearchResult, bool) {
+	if opts.TT == nil {
+		opts.TT = NewTransTable(DefaultTTSizeMB)
+	}
+	if opts.Eval == nil {
+		opts.Eval = &DefaultEvalParams
+	}
+	start := time.Now()
+	s := newSearcher(NewHashedBoard(b, color), opts, start, new(atomic.Bool))
+
+	var rootMoves []AmazonMove
+	for _, m := range s.board.GenerateMoves(color, nil) {
+		if !slices.Contains(opts.Exclude, m) {
+			rootMoves = append(rootMoves, m)
+		}
+	}
+	if len(rootMoves) == 0 {
+		return SearchResult{}, false
+	}
+	if len(rootMoves) == 1 {
+		return SearchResult{Move: rootMoves[0]}, true
+	}
+	// 第一轮迭代之前按静态预评分排列根着法，之后按上一轮的得分排列
+	if opts.NoOrdering&OrderStatic == 0 {
+		var near neighborhood
+		b.neighborhood(color, &near)
+		scores := make([]float64, len(rootMoves))
+		for i, m := range rootMoves {
+			scores[i] = float64(near.score(m))
+		}
+		sort.Stable(rootOrder{rootMoves, scores})
+	}
+
+	var wg sync.WaitGroup
+	helpers := make([]*searcher, max(opts.Threads-1, 0))
+	for i := range helpers {
+		board := *b
+		h := newSearcher(NewHashedBoard(&board, color), opts, start, s.stop)
+		h.opts.Info = nil
+		helpers[i] = h
+		// 第1、3、5…个辅助线程（下标i为偶数）从深度2开始，并把根着法轮转不同的位置，使各线程尽量搜索不同的子树
+		moves := slices.Clone(rootMoves)
+		rotate(moves, (i+1)*len(moves)/len(helpers)/2)
+		wg.Add(1)
+		go func(first int) {
+			defer wg.Done()
+			h.iterate(moves, color, first)
+		}(1 + (i+1)%2)
+	}
+
+	best := s.iterate(rootMoves, color, 1)
+	s.stop.Store(true)
+	wg.Wait()
+	best.Nodes = s.nodes
+	for _, h := range helpers {
+		best.Nodes += h.nodes
+	}
+	best.Elapsed = time.Since(start)
+	return best, true
+}
+
+func newSearcher(board *HashedBoard, opts SearchOptions, start time.Time, stop *atomic.Bool) *searcher {
+	s := &searcher{board: board, tt: opts.TT, opts: opts, start: start, stop: stop}
+	if opts.NoOrdering&OrderHistory == 0 {
+		s.history = new(historyTable)
+	}
+	return s
+}
+
+// 从深度first开始对根着法迭代加深，返回最后一轮完整迭代的结果
+func (s *searcher) iterate(rootMoves []AmazonMove, color, first int) SearchResult {
+	best := SearchResult{Move: rootMoves[0]}
+	scores := make([]float64, len(rootMoves))
+	for depth := first; depth <= maxPly && (s.opts.MaxDepth == 0 || depth <= s.opts.MaxDepth); depth++ {
+		alpha := math.Inf(-1)
+		bestIndex := -1
+		for i, m := range rootMoves {
+			s.board.Move(m)
+			score := -s.negamax(depth-1, 1, math.Inf(-1), -alpha, 3-color)
+			s.board.UndoMove(m)
+			if s.stopped {
+				break
+			}
+			scores[i] = score
+			if score > alpha {
+				alpha = score
+				bestIndex = i
+			}
+		}
+		if s.stopped {
+			if depth == first && bestIndex >= 0 {
+				best = SearchResult{Move: rootMoves[bestIndex], Score: alpha, Depth: 0}
+			}
+			break
+		}
+
+		// 最佳着法排在最前，其余着法按得分从高到低排序
+		scores[0], scores[bestIndex] = scores[bestIndex], scores[0]
+		rootMoves[0], rootMoves[bestIndex] = rootMoves[bestIndex], rootMoves[0]
+		sort.Stable(rootOrder{rootMoves[1:], scores[1:]})
+
+		best = SearchResult{Move: rootMoves[0], Score: alpha, Depth: depth}
+		s.tt.Store(s.board.Key, TTEntry{Depth: depth, Bound: BoundExact, Score: scoreToTT(alpha, 0), Move: best.Move, HasMove: true})
+		if s.opts.Info != nil {
+			best.Nodes, best.Elapsed = s.nodes, time.Since(s.start)
+			s.opts.Info(best)
+		}
+		// 已经找到必胜或必败的着法，无需继续加深
+		if math.Abs(alpha) >= MateScore-maxPly {
+			break
+		}
+	}
+	return best
+}
+
+// 将moves循环左移k位
+func rotate(moves []AmazonMove, k int) {
+	if len(moves) == 0 {
+		return
+	}
+	k %= len(moves)
+	slices.Reverse(moves[:k])
+	slices.Reverse(moves[k:])
+	slices.Reverse(moves)
+}
+
+// 负极大值形式的Alpha-Beta搜索，返回以color一方视角的分值
+func (s *searcher) negamax(depth, ply int, alpha, beta float64, color int) float64 {
+	s.nodes++
+	if s.timeUp() {
+		return 0
+	}
+	if !s.board.HasMoves(color) {
+		return -MateScore + float64(ply) // 无子可动的一方判负
+	}
+	if depth == 0 || ply >= maxPly {
+		return s.evaluate(color, ply)
+	}
+
+	// 查询置换表：深度足够时直接截断，否则只用其中的最佳着法排序
+	alphaOrig := alpha
+	entry, hit := s.tt.Probe(s.board.Key)
+	if hit && entry.Depth >= depth {
+		score := scoreFromTT(entry.Score, ply)
+		switch {
+		case entry.Bound == BoundExact,
+			entry.Bound == BoundLower && score >= beta,
+			entry.Bound == BoundUpper && score <= alpha:
+			return score
+		}
+	}
+	moves := s.board.GenerateMoves(color, s.moveBuf[ply][:0])
+	s.moveBuf[ply] = moves
+	scores := s.orderMoves(moves, ply, color, entry.Move, hit && entry.HasMove)
+
+	best := math.Inf(-1)
+	var bestMove AmazonMove
+	for i := range moves {
+		nextMove(moves, scores, i)
+		m := moves[i]
+		s.board.Move(m)
+		score := -s.negamax(depth-1, ply+1, -beta, -alpha, 3-color)
+		s.board.UndoMove(m)
+		if s.stopped {
+			return 0
+		}
+		if score > best {
+			best, bestMove = score, m
+			if score > alpha {
+				alpha = score
+				if alpha >= beta {
+					s.cutoff(m, depth, ply)
+					break
+				}
+			}
+		}
+	}
+
+	bound := BoundExact
+	switch {
+	case best <= alphaOrig:
+		bound = BoundUpper
+	case best >= beta:
+		bound = BoundLower
+	}
+	s.tt.Store(s.board.Key, TTEntry{Depth: depth, Bound: bound, Score: scoreToTT(best, ply), Move: bestMove, HasMove: true})
+	return best
+}
+
+// 以color一方视角评估当前局面，评估函数的阶段按到达该节点时的步数计算
+func (s *searcher) evaluate(color, ply int) float64 {
+	return s.opts.Eval.Evaluate(s.board.AmazonBoard, s.opts.Step+ply, color)
+}
+
+// 每隔一定节点数检查一次是否到达截止时间、被主线程通知结束或收到外部停止信号
+func (s *searcher) timeUp() bool {
+	if !s.stopped && s.nodes&63 == 0 && (s.stop.Load() || s.opts.Stop != nil && s.opts.Stop.Load() ||
+		!s.opts.Deadline.IsZero() && time.Now().After(s.opts.Deadline)) {
+		s.stopped = true
+	}
+	return s.stopped
+}
+
+// 置换表中局面b上color一方的最佳着法，用于预测对手应着；记录不存在或着法不合法时返回false
+func (t *TransTable) BestMove(b *AmazonBoard, color int) (AmazonMove, bool) {
+	entry, ok := t.Probe(b.HashFor(color))
+	if !ok || !entry.HasMove || b.CheckMove(entry.Move, color) != nil {
+		return AmazonMove{}, false
+	}
+	return entry.Move, true
+}
+
+// 根节点着法与得分的联合排序，得分高者在前
+type rootOrder struct {
+	moves  []AmazonMove
+	scores []float64
+}
+
+func (r rootOrder) Len() int           { return len(r.moves) }
+func (r rootOrder) Less(i, j int) bool { return r.scores[i] > r.scores[j] }
+func (r rootOrder) Swap(i, j int) {
+	r.moves[i], r.moves[j] = r.moves[j], r.moves[i]
+	r.scores[i], r.scores[j] = r.scores[j], r.scores[i]
+}
@@ -0,0 +1,148 @@
+package amazon
+
+import (
+	"fmt"
+	"math/rand"
+	"runtime"
+	"slices"
+	"testing"
+)
+
+// 固定的基准局面：从初始局面随机走不同步数得到的中局和残局
+func searchSuite() []struct {
+	board *AmazonBoard
+	color int
+} {
+	r := rand.New(rand.NewSource(21))
+	var suite []struct {
+		board *AmazonBoard
+		color int
+	}
+	for _, plies := range []int{40, 44, 48, 52} {
+		b, color := randomPosition(r, plies)
+		suite = append(suite, struct {
+			board *AmazonBoard
+			color int
+		}{b, color})
+	}
+	return suite
+}
+
+func TestSearchThreads(t *testing.T) {
+	for i, p := range searchSuite() {
+		before := *p.board
+		res, ok := p.board.Search(p.color, SearchOptions{Step: 20, MaxDepth: 2, Threads: 4})
+		if !ok {
+			t.Fatalf("position %d: no move found", i)
+		}
+		if *p.board != before {
+			t.Fatalf("position %d: search modified the board", i)
+		}
+		if !slices.Contains(p.board.GenerateMoves(p.color, nil), res.Move) {
+			t.Fatalf("position %d: illegal move %s", i, res.Move.Notation())
+		}
+		if res.Depth != 2 {
+			t.Fatalf("position %d: depth %d, want 2", i, res.Depth)
+		}
+	}
+}
+
+/*
+* 比较不同线程数搜到深度5的用时（time-to-depth），每次操作搜完整个基准局面集
+* Lazy SMP的加速体现为每次操作的耗时随线程数下降；深度太浅时辅助线程来不及向置换表
+* 提供有用的结果，只剩额外开销。线程数超过CPU核数时不会加速
+ */
+func BenchmarkSearchThreads(b *testing.B) {
+	const depth = 5
+	suite := searchSuite()
+	counts := []int{1, 2, 4, runtime.NumCPU()}
+	slices.Sort(counts)
+	for _, threads := range slices.Compact(counts) {
+		b.Run(fmt.Sprintf("threads=%d", threads), func(b *testing.B) {
+			var nodes int64
+			for i := 0; i < b.N; i++ {
+				for _, p := range suite {
+					res, _ := p.board.Search(p.color, SearchOptions{Step: 20, MaxDepth: depth, Threads: threads})
+					if res.Depth != depth {
+						b.Fatalf("reached depth %d, want %d", res.Depth, depth)
+					}
+					nodes += res.Nodes
+				}
+			}
+			b.ReportMetric(float64(nodes)/float64(b.N), "nodes/op")
+		})
+	}
+}
+
+func TestSearchOrdering(t *testing.T) {
+	var unordered, ordered int64
+	for i, p := range searchSuite() {
+		plain, _ := p.board.Search(p.color, SearchOptions{Step: 20, MaxDepth: 2, NoOrdering: OrderAll})
+		res, _ := p.board.Search(p.color, SearchOptions{Step: 20, MaxDepth: 2})
+		// 排序只影响剪枝，不改变固定深度的搜索值
+		if !almostEqual(plain.Score, res.Score) {
+			t.Fatalf("position %d: ordered score %v, unordered %v", i, res.Score, plain.Score)
+		}
+		unordered += plain.Nodes
+		ordered += res.Nodes
+	}
+	if ordered >= unordered {
+		t.Fatalf("ordering searched %d nodes, unordered %d", ordered, unordered)
+	}
+}
+
+func TestStaticScore(t *testing.T) {
+	b := parseBoard(t,
+		"XXXXXXXXXX",
+		"XXXXXXXXXX",
+		"XXXXXXXXXX",
+		"XXX.....XX",
+		"XXX.B...XX",
+		"XXX...W.XX",
+		"XXX.....XX",
+		"XXXXXXXXXX",
+		"XXXXXXXXXX",
+		"XXXXXXXXXX",
+	)
+	// 走向开阔处并把障碍贴在对方棋子旁的着法优于钻进角落并堵住自己的着法
+	good := AmazonMove{From: Position{4, 4}, To: Position{4, 5}, Put: Position{4, 6}}
+	bad := AmazonMove{From: Position{4, 4}, To: Position{3, 3}, Put: Position{3, 4}}
+	for _, m := range []AmazonMove{good, bad} {
+		if err := b.CheckMove(m, Black); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var near neighborhood
+	b.neighborhood(Black, &near)
+	if g, w := near.score(good), near.score(bad); g <= w {
+		t.Fatalf("static score good %d, bad %d", g, w)
+	}
+}
+
+// 比较各组着法排序启发搜到固定深度4的节点数；深度2时置换表只存有根节点的着法，须更深才能体现置换表着法的效果
+func BenchmarkSearchOrdering(b *testing.B) {
+	suite := searchSuite()
+	// 依次多启用一项启发
+	configs := []struct {
+		name string
+		off  Ordering
+	}{
+		{"none", OrderAll},
+		{"tt", OrderKillers | OrderHistory | OrderStatic},
+		{"tt+killers", OrderHistory | OrderStatic},
+		{"tt+killers+history", OrderStatic},
+		{"all", 0},
+	}
+	for _, cfg := range configs {
+		b.Run(cfg.name, func(b *testing.B) {
+			var nodes int64
+			for i := 0; i < b.N; i++ {
+				for _, p := range suite {
+					res, _ := p.board.Search(p.color, SearchOptions{Step: 20, MaxDepth: 4, NoOrdering: cfg.off})
+					nodes += res.Nodes
+				}
+			}
+			b.ReportMetric(float64(nodes)/float64(b.N), "nodes/op")
+		})
+	}
+}
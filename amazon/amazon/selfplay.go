@@ -0,0 +1,158 @@
+// 引擎自对弈生成训练数据，每个局面连同搜索分值、所选着法和最终胜负写成一行JSON。
+package amazon
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"strings"
+)
+
+// SelfPlayPosition 为自对弈中经过搜索的一个局面，对应JSONL文件中的一行
+type SelfPlayPosition struct {
+	Board  string         `json:"board"`            // 局面，见 EncodeBoard
+	Color  int            `json:"color"`            // 行棋方
+	Turn   int            `json:"turn"`             // 步数，从1开始
+	Score  float64        `json:"score"`            // 行棋方视角的搜索分值，含义见Scale
+	Scale  string         `json:"scale"`            // 分值的尺度，ScaleEval或ScaleWinRate
+	Move   string         `json:"move"`             // 所选着法，SAU格式
+	Visits map[string]int `json:"visits,omitempty"` // UCT搜索时根节点各着法的访问次数
+	Winner int            `json:"winner"`           // 该局的获胜方
+}
+
+// 自对弈数据中分值的尺度
+const (
+	ScaleEval    = "eval"    // Alpha-Beta的评估分值，无固定范围，必胜/必败时接近±MateScore
+	ScaleWinRate = "winrate" // 蒙特卡洛树搜索的平均胜率，在[0,1]之间，0.5为均势
+)
+
+// MoveChooser 为自对弈中每步调用的搜索，返回着法、行棋方视角的分值和（UCT搜索时的）根节点访问次数
+type MoveChooser func(b *AmazonBoard, color, turn int) (move AmazonMove, score float64, visits map[AmazonMove]int, ok bool)
+
+// SelfPlayOptions 配置自对弈
+type SelfPlayOptions struct {
+	RandomPlies int         // 开局随机走的步数，这些局面不记录
+	Choose      MoveChooser // 其余各步的搜索
+	Scale       string      // Choose返回分值的尺度，原样记入各局面
+}
+
+/*
+* 自对弈一局
+* 先从初始局面随机走RandomPlies步，再由双方轮流调用Choose直到一方无子可动
+* 返回搜索过的局面和获胜方，局面的Winner已填好
+ */
+func SelfPlay(opts SelfPlayOptions, r *rand.Rand) ([]SelfPlayPosition, int) {
+	b := NewBoard()
+	color := Black
+	turn := 1
+	var positions []SelfPlayPosition
+	for ; ; turn++ {
+		moves := b.GenerateMoves(color, nil)
+		if len(moves) == 0 {
+			break
+		}
+		if turn <= opts.RandomPlies {
+			b.Move(moves[r.Intn(len(moves))])
+			color = 3 - color
+			continue
+		}
+		m, score, visits, ok := opts.Choose(b, color, turn)
+		if !ok {
+			break
+		}
+		pos := SelfPlayPosition{Board: b.EncodeBoard(), Color: color, Turn: turn, Score: score, Scale: opts.Scale, Move: m.Notation()}
+		if len(visits) > 0 {
+			pos.Visits = make(map[string]int, len(visits))
+			for vm, n := range visits {
+				pos.Visits[vm.Notation()] = n
+			}
+		}
+		positions = append(positions, pos)
+		b.Move(m)
+		color = 3 - color
+	}
+	winner := 3 - color // 无子可动的一方判负
+	for i := range positions {
+		positions[i].Winner = winner
+	}
+	return positions, winner
+}
+
+// 将局面按行优先编码为100个字符，空格、黑、白、障碍分别为'.'、'B'、'W'、'X'
+func (b *AmazonBoard) EncodeBoard() string {
+	var sb strings.Builder
+	sb.Grow(100)
+	for x := 0; x < 10; x++ {
+		for y := 0; y < 10; y++ {
+			sb.WriteByte(".BWX"[b[x][y]])
+		}
+	}
+	return sb.String()
+}
+
+// 解析 EncodeBoard 编码的局面
+func DecodeBoard(s string) (*AmazonBoard, error) {
+	if len(s) != 100 {
+		return nil, fmt.Errorf("board %q: want 100 squares, got %d", s, len(s))
+	}
+	b := &AmazonBoard{}
+	for i := 0; i < 100; i++ {
+		v := strings.IndexByte(".BWX", s[i])
+		if v < 0 {
+			return nil, fmt.Errorf("board %q: invalid square %q", s, s[i])
+		}
+		b[i/10][i%10] = v
+	}
+	return b, nil
+}
+
+// 将局面逐行写入JSONL
+func WriteSelfPlay(w io.Writer, positions []SelfPlayPosition) error {
+	enc := json.NewEncoder(w)
+	for i := range positions {
+		if err := enc.Encode(&positions[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// 读取JSONL格式的自对弈数据，空行忽略
+func ReadSelfPlay(r io.Reader) ([]SelfPlayPosition, error) {
+	var positions []SelfPlayPosition
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 0, 64*1024), 1<<20)
+	for line := 1; sc.Scan(); line++ {
+		if strings.TrimSpace(sc.Text()) == "" {
+			continue
+		}
+		var p SelfPlayPosition
+		if err := json.Unmarshal(sc.Bytes(), &p); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		positions = append(positions, p)
+	}
+	return positions, sc.Err()
+}
+
+// 为自对弈局面生成训练样本，没有胜负的局面跳过
+func SelfPlaySamples(positions []SelfPlayPosition) ([]TuneSample, error) {
+	samples := make([]TuneSample, 0, len(positions))
+	for _, p := range positions {
+		if p.Winner != Black && p.Winner != White {
+			continue
+		}
+		b, err := DecodeBoard(p.Board)
+		if err != nil {
+			return nil, err
+		}
+		result := 0.0
+		if p.Winner == Black {
+			result = 1
+		}
+		samples = append(samples, TuneSample{Terms: b.CalculateEvalTerms(), Turn: p.Turn, Result: result})
+	}
+	return samples, nil
+}
@@ -0,0 +1,109 @@
+package amazon
+
+import (
+	"bytes"
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestEncodeBoard(t *testing.T) {
+	r := rand.New(rand.NewSource(5))
+	for i := 0; i < 50; i++ {
+		b, _ := randomPosition(r, r.Intn(90))
+		s := b.EncodeBoard()
+		got, err := DecodeBoard(s)
+		if err != nil {
+			t.Fatalf("position %d: %v", i, err)
+		}
+		if *got != *b {
+			t.Fatalf("position %d: decoded board differs from %s", i, s)
+		}
+	}
+	// 初始局面第0行第3列为白棋，第9行第3列为黑棋
+	if s := NewBoard().EncodeBoard(); s[3] != 'W' || s[93] != 'B' || strings.Count(s, ".") != 92 {
+		t.Errorf("initial board encoded as %s", s)
+	}
+
+	for _, bad := range []string{"", strings.Repeat(".", 99), strings.Repeat(".", 101), strings.Repeat(".", 99) + "b"} {
+		if _, err := DecodeBoard(bad); err == nil {
+			t.Errorf("DecodeBoard(%q) accepted an invalid board", bad)
+		}
+	}
+}
+
+func TestSelfPlay(t *testing.T) {
+	r := rand.New(rand.NewSource(6))
+	// 一层搜索足以走完对局；访问次数只给所选着法，检验其写入与读回
+	choose := func(b *AmazonBoard, color, turn int) (AmazonMove, float64, map[AmazonMove]int, bool) {
+		res, ok := b.Search(color, SearchOptions{Step: turn, MaxDepth: 1})
+		return res.Move, res.Score, map[AmazonMove]int{res.Move: turn}, ok
+	}
+	const randomPlies = 30
+	var buf bytes.Buffer
+	var all []SelfPlayPosition
+	for game := 0; game < 2; game++ {
+		positions, winner := SelfPlay(SelfPlayOptions{RandomPlies: randomPlies, Choose: choose, Scale: ScaleEval}, r)
+		if len(positions) == 0 {
+			t.Fatalf("game %d recorded no positions", game)
+		}
+		// 逐个复盘：步数连续、着法合法，前一局面走完着法后得到后一局面
+		var next *AmazonBoard
+		for i, p := range positions {
+			b, err := DecodeBoard(p.Board)
+			if err != nil {
+				t.Fatalf("game %d position %d: %v", game, i, err)
+			}
+			if next != nil && *b != *next {
+				t.Fatalf("game %d position %d does not follow from the previous move", game, i)
+			}
+			if p.Turn != randomPlies+1+i || p.Color != 2-p.Turn%2 || p.Scale != ScaleEval || p.Winner != winner {
+				t.Fatalf("game %d position %d: turn %d color %d scale %q winner %d", game, i, p.Turn, p.Color, p.Scale, p.Winner)
+			}
+			m, err := b.ParseLegalMove(p.Move, p.Color)
+			if err != nil {
+				t.Fatalf("game %d position %d: %v", game, i, err)
+			}
+			if p.Visits[p.Move] != p.Turn {
+				t.Fatalf("game %d position %d: visits %v", game, i, p.Visits)
+			}
+			b.Move(m)
+			next = b
+		}
+		// 最后一步之后对方无子可动，走出最后一步的一方获胜
+		if last := positions[len(positions)-1]; winner != last.Color || next.HasMoves(3-winner) {
+			t.Fatalf("game %d: winner %d after %s moved last", game, winner, []string{"", "black", "white"}[last.Color])
+		}
+		if err := WriteSelfPlay(&buf, positions); err != nil {
+			t.Fatal(err)
+		}
+		buf.WriteString("\n") // 空行被忽略
+		all = append(all, positions...)
+	}
+
+	read, err := ReadSelfPlay(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(read, all) {
+		t.Fatalf("read back %d positions, differing from the %d written", len(read), len(all))
+	}
+	samples, err := SelfPlaySamples(read)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(samples) != len(all) {
+		t.Fatalf("%d samples for %d positions", len(samples), len(all))
+	}
+	for i, s := range samples {
+		b, _ := DecodeBoard(all[i].Board)
+		if s.Terms != b.CalculateEvalTerms() || s.Turn != all[i].Turn || (s.Result == 1) != (all[i].Winner == Black) {
+			t.Fatalf("sample %d does not match position %d", i, i)
+		}
+	}
+
+	if _, err := ReadSelfPlay(strings.NewReader("{\"board\": 1}\n")); err == nil || !strings.Contains(err.Error(), "line 1") {
+		t.Errorf("ReadSelfPlay with a malformed line = %v", err)
+	}
+}
@@ -0,0 +1,176 @@
+// 实现残局的精确胜负求解，结合区域分解与置换表做全宽度搜索。
+package amazon
+
+import (
+	"slices"
+	"time"
+)
+
+// 争夺区域内空格数不超过该值时才尝试精确求解
+// 与穷举区域步数的上限一致，保证求解过程中从争夺区域分裂出的单方区域都能算出精确步数
+const SolveThreshold = exactRegionSize
+
+// 已证明的局面：行棋方是否必胜，必胜时附带致胜着法
+type solved struct {
+	win  bool
+	move AmazonMove
+}
+
+// 求解中的局面，除棋盘外还包括双方在冻结区域内已走的步数
+type solveKey struct {
+	hash uint64
+	used [3]int
+}
+
+/*
+* 空格过多、只能算出步数下界的单方区域在求解中保持冻结：不在其中展开着法，只当作若干步可随时走的空着
+* 步数按对求解方不利的方向估计：求解方的区域取贪心下界，对方的区域取空格数（每步消耗一个空格，不会更多）
+* 这样证明的必胜在实际步数下仍然成立，但证明的必败不一定成立
+ */
+type solver struct {
+	board   *HashedBoard
+	cache   *fillCache
+	table   map[solveKey]solved // 已证明的局面
+	root    int                 // 求解方
+	used    [3]int              // 双方在冻结区域内已走的步数
+	stopped bool
+	nodes   int64
+	end     time.Time
+}
+
+// 检查局面是否适合精确求解：争夺区域空格数足够少；其余单方区域无论大小都不影响
+func (b *AmazonBoard) Solvable() bool {
+	return b.Regions().Solvable()
+}
+
+func (rs Partition) Solvable() bool {
+	contested := 0
+	for _, r := range rs {
+		if r.Kind == RegionContested {
+			contested += r.Squares.Count()
+		}
+	}
+	return contested <= SolveThreshold
+}
+
+// 精确求解color一方行棋时的胜负，见 SolveRegions
+func (b *AmazonBoard) Solve(color int, deadline time.Time) (win bool, move AmazonMove, ok bool) {
+	return b.SolveRegions(b.Regions(), color, deadline)
+}
+
+/*
+* 精确求解color一方行棋时的胜负，regions为调用方已划分好的当前局面的区域
+* 每个节点先划分区域：没有争夺区域时直接比较双方步数决定胜负
+* 否则只展开争夺区域内的全部着法，单方区域内只取最优填充的一步（其余填充不会更好），冻结区域内只走空着
+* 必胜时返回一个致胜着法，在冻结区域内走空着时返回该区域贪心填充的第一步
+* 在deadline前未能完成、局面不适合求解，或存在冻结区域时未能证明必胜，ok为false
+ */
+func (b *AmazonBoard) SolveRegions(regions Partition, color int, deadline time.Time) (win bool, move AmazonMove, ok bool) {
+	if !regions.Solvable() {
+		return false, AmazonMove{}, false
+	}
+	s := &solver{
+		board: NewHashedBoard(b, color),
+		cache: newFillCache(),
+		table: make(map[solveKey]solved),
+		root:  color,
+		end:   deadline,
+	}
+	win, move = s.solve(color)
+	if s.stopped {
+		return false, AmazonMove{}, false
+	}
+	if !win && slices.ContainsFunc(regions, func(r Region) bool { return r.Owner() != Empty && !r.Exact }) {
+		return false, AmazonMove{}, false // 冻结区域的步数是估计值，必败未经证明
+	}
+	return win, move, true
+}
+
+func (s *solver) solve(color int) (bool, AmazonMove) {
+	s.nodes++
+	if s.nodes&255 == 0 && !s.end.IsZero() && time.Now().After(s.end) {
+		s.stopped = true
+	}
+	if s.stopped {
+		return false, AmazonMove{}
+	}
+	key := solveKey{hash: s.board.Key, used: s.used}
+	if e, ok := s.table[key]; ok {
+		return e.win, e.move
+	}
+
+	p := s.board.Bits
+	var moves []AmazonMove
+	var count, frozen [3]int // 双方在精确区域和冻结区域内的步数
+	var pass AmazonMove      // 在本方冻结区域内走空着时实际走出的着法
+	contested := false
+	for _, r := range p.regions(s.cache) {
+		owner := r.Owner()
+		switch {
+		case r.Kind == RegionContested:
+			contested = true
+			moves = p.generateMovesFrom(p.Pieces(color).And(r.Black.Or(r.White)), moves)
+		case r.Kind == RegionDead:
+		case !r.Exact:
+			n := r.Moves
+			if owner != s.root {
+				n = r.Squares.Count()
+			}
+			frozen[owner] += n
+			if owner == color && r.Moves > 0 {
+				pass = r.first
+			}
+		default:
+			count[owner] += r.Moves
+			if owner == color && r.Moves > 0 {
+				moves = append(moves, r.first)
+			}
+		}
+	}
+	passes := frozen[color] - s.used[color] // 本方还能走的空着数
+	if len(moves) == 0 && passes <= 0 {
+		return false, AmazonMove{} // 无子可动的一方判负
+	}
+	// 区域全部确定：行棋方步数多于对方才能获胜，步数相同时行棋方先走完
+	if !contested {
+		own := count[color] + passes
+		opp := count[3-color] + frozen[3-color] - s.used[3-color]
+		if len(moves) == 0 {
+			return own > opp, pass
+		}
+		return own > opp, moves[0]
+	}
+
+	for _, m := range moves {
+		s.board.Move(m)
+		childWin, _ := s.solve(3 - color)
+		s.board.UndoMove(m)
+		if s.stopped {
+			return false, AmazonMove{}
+		}
+		if !childWin {
+			s.table[key] = solved{win: true, move: m}
+			return true, m
+		}
+	}
+	if passes > 0 {
+		s.used[color]++
+		s.board.Key ^= zobristSide // 空着只改变行棋方
+		childWin, _ := s.solve(3 - color)
+		s.board.Key ^= zobristSide
+		s.used[color]--
+		if s.stopped {
+			return false, AmazonMove{}
+		}
+		if !childWin {
+			s.table[key] = solved{win: true, move: pass}
+			return true, pass
+		}
+	}
+	fallback := pass
+	if len(moves) > 0 {
+		fallback = moves[0]
+	}
+	s.table[key] = solved{win: false, move: fallback}
+	return false, fallback
+}
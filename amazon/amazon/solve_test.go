@@ -0,0 +1,188 @@
+package amazon
+
+import (
+	"testing"
+	"time"
+)
+
+// 穷举博弈树判断color一方行棋时是否必胜，无子可动的一方判负；只用于很小的局面
+func bruteWin(b *AmazonBoard, color int) bool {
+	for _, m := range b.GenerateMoves(color, nil) {
+		child := *b
+		child.Move(m)
+		if !bruteWin(&child, 3-color) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestSolve(t *testing.T) {
+	sealed := func(rows ...string) *AmazonBoard {
+		full := []string{
+			"XXXXXXXXXX", "XXXXXXXXXX", "XXXXXXXXXX", "XXXXXXXXXX", "XXXXXXXXXX",
+			"XXXXXXXXXX", "XXXXXXXXXX", "XXXXXXXXXX", "XXXXXXXXXX", "XXXXXXXXXX",
+		}
+		copy(full, rows)
+		return parseBoard(t, full...)
+	}
+	tests := []struct {
+		name  string
+		board *AmazonBoard
+		color int
+		win   bool
+	}{
+		// 归属已定：步数多的一方获胜，步数相同时行棋方先走完而落败
+		{"more moves", sealed("X.B..XXXXX", "XXXXXXXXXX", "W..XXXXXXX"), Black, true},
+		{"fewer moves", sealed("X.B..XXXXX", "XXXXXXXXXX", "W..XXXXXXX"), White, false},
+		{"equal moves", sealed("B..XXXXXXX", "XXXXXXXXXX", "W..XXXXXXX"), Black, false},
+		// 争夺中的一格：行棋方占住后对方无子可动
+		{"contested square", sealed("B.WXXXXXXX"), Black, true},
+		{"contested square white", sealed("B.WXXXXXXX"), White, true},
+		// 黑方占住争夺格后只剩零步，白方另有两步
+		{"contested square behind", sealed("B.WXXXXXXX", "XXXXXXXXXX", "W..XXXXXXX"), Black, false},
+		{"contested square ahead", sealed("B.WXXXXXXX", "XXXXXXXXXX", "W..XXXXXXX"), White, true},
+		// 争夺区域较大，需要搜索多步，结果已由穷举验证
+		{"contested corridor", sealed("B....WXXXX", "XXXXXXXXXX", "W.XXXXXXXX"), Black, true},
+		{"contested block", sealed("B..XXXXXXX", "..WXXXXXXX"), Black, true},
+		{"contested block behind", sealed("B..XXXXXXX", "..WXXXXXXX", "XXXXXXXXXX", "W..XXXXXXX"), Black, false},
+		{"contested block ahead", sealed("B..XXXXXXX", "..WXXXXXXX", "XXXXXXXXXX", "W..XXXXXXX"), White, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if brute := bruteWin(tt.board, tt.color); brute != tt.win {
+				t.Fatalf("brute force says win %v, want %v", brute, tt.win)
+			}
+			before := *tt.board
+			win, m, ok := tt.board.Solve(tt.color, time.Time{})
+			if !ok {
+				t.Fatal("Solve did not finish")
+			}
+			if *tt.board != before {
+				t.Fatal("Solve modified the board")
+			}
+			if win != tt.win {
+				t.Fatalf("Solve win = %v, want %v", win, tt.win)
+			}
+			if !win {
+				return
+			}
+			// 致胜着法合法，且走完后对方必败
+			if err := tt.board.CheckMove(m, tt.color); err != nil {
+				t.Fatalf("winning move %v: %v", m, err)
+			}
+			child := *tt.board
+			child.Move(m)
+			if bruteWin(&child, 3-tt.color) {
+				t.Errorf("move %s does not win", m.Notation())
+			}
+		})
+	}
+}
+
+func TestSolvableThreshold(t *testing.T) {
+	// 争夺区域恰好SolveThreshold个空格
+	atThreshold := parseBoard(t,
+		"B......XXX",
+		"......WXXX",
+		"XXXXXXXXXX",
+		"XXXXXXXXXX",
+		"XXXXXXXXXX",
+		"XXXXXXXXXX",
+		"XXXXXXXXXX",
+		"XXXXXXXXXX",
+		"XXXXXXXXXX",
+		"XXXXXXXXXX",
+	)
+	// 多一个空格
+	above := parseBoard(t,
+		"B......XXX",
+		"......W.XX",
+		"XXXXXXXXXX",
+		"XXXXXXXXXX",
+		"XXXXXXXXXX",
+		"XXXXXXXXXX",
+		"XXXXXXXXXX",
+		"XXXXXXXXXX",
+		"XXXXXXXXXX",
+		"XXXXXXXXXX",
+	)
+	// 争夺区域不变，另有一个只能算出步数下界的大区域，求解时冻结而不妨碍求解
+	inexact := parseBoard(t,
+		"B......XXX",
+		"......WXXX",
+		"XXXXXXXXXX",
+		"XXXXXXXXXX",
+		"B.........",
+		"....XXXXXX",
+		"XXXXXXXXXX",
+		"XXXXXXXXXX",
+		"XXXXXXXXXX",
+		"XXXXXXXXXX",
+	)
+	if SolveThreshold != 12 {
+		t.Fatalf("boards are built for SolveThreshold 12, got %d", SolveThreshold)
+	}
+	tests := []struct {
+		name     string
+		board    *AmazonBoard
+		solvable bool
+	}{
+		{"at threshold", atThreshold, true},
+		{"above threshold", above, false},
+		{"inexact region", inexact, true},
+	}
+	for _, tt := range tests {
+		if got := tt.board.Solvable(); got != tt.solvable {
+			t.Errorf("%s: Solvable() = %v, want %v", tt.name, got, tt.solvable)
+		}
+		if _, _, ok := tt.board.Solve(Black, time.Now().Add(time.Second)); !tt.solvable && ok {
+			t.Errorf("%s: Solve finished on an unsolvable board", tt.name)
+		}
+	}
+}
+
+func TestSolveFrozenRegions(t *testing.T) {
+	// 争夺区域只有一格；黑方另有19个空格的大区域，白方另有两个精确区域
+	b := parseBoard(t,
+		"B.WXXXXXXX",
+		"XXXXXXXXXX",
+		"B.........",
+		".........X",
+		"XXXXXXXXXX",
+		"W...XXXXXX",
+		"XXXXXXXXXX",
+		"W....XXXXX",
+		"XXXXXXXXXX",
+		"XXXXXXXXXX",
+	)
+	regions := b.Regions()
+	large := regionAt(t, regions, 2, 0)
+	if large.Kind != RegionBlack || large.Exact {
+		t.Fatalf("large region: kind %d exact %v, want an inexact black region", large.Kind, large.Exact)
+	}
+	if !regions.Solvable() {
+		t.Fatal("a large owned region disabled solving")
+	}
+	// 黑方冻结区域按贪心下界计步，仍多于白方的3+4步，无论谁先走都是黑胜
+	win, m, ok := b.SolveRegions(regions, Black, time.Time{})
+	if !ok || !win {
+		t.Fatalf("black to move: win %v ok %v, want a proven win", win, ok)
+	}
+	if err := b.CheckMove(m, Black); err != nil {
+		t.Fatalf("winning move %v: %v", m, err)
+	}
+	// 白方必败，但黑方区域的步数是估计值，必败无法证明
+	if _, _, ok := b.SolveRegions(regions, White, time.Time{}); ok {
+		t.Error("white to move: reported a loss that depends on an estimated region")
+	}
+
+	// 交换颜色后结论相同：白方求解时自己的大区域按下界计步，黑方求解时对方的大区域按空格数计步
+	mirrored := mirrorColors(b)
+	if win, _, ok := mirrored.Solve(White, time.Time{}); !ok || !win {
+		t.Errorf("mirrored, white to move: win %v ok %v, want a proven win", win, ok)
+	}
+	if _, _, ok := mirrored.Solve(Black, time.Time{}); ok {
+		t.Error("mirrored, black to move: reported a result that depends on an estimated region")
+	}
+}
@@ -0,0 +1,149 @@
+// 实现固定大小、无锁的置换表，按局面哈希保存搜索结果。
+package amazon
+
+import (
+	"math"
+	"sync/atomic"
+)
+
+const DefaultTTSizeMB = 64 // 默认置换表大小(MB)
+
+// Bound 表示置换表中分值的类型
+type Bound uint8
+
+const (
+	BoundExact Bound = iota // 精确值
+	BoundLower              // 下界，搜索时发生了beta截断
+	BoundUpper              // 上界，所有着法都没有超过alpha
+)
+
+// TTEntry 为置换表中的一条记录
+type TTEntry struct {
+	Depth   int        // 搜索深度
+	Bound   Bound      // 分值类型
+	Score   float64    // 分值
+	Move    AmazonMove // 最佳着法
+	HasMove bool       // Move是否有效
+}
+
+/*
+* 每个槽位保存两个64位字：key^data 与 data
+* 读写都是原子操作，不加锁；并发写入造成的撕裂记录在校验key时会被丢弃
+* data的布局：
+* 0-31位  分值(float32)
+* 32-39位 深度
+* 40-41位 分值类型
+* 42-62位 着法，起点、终点、障碍各7位(x*10+y)，全为1表示没有着法
+ */
+type ttSlot struct {
+	check atomic.Uint64
+	data  atomic.Uint64
+}
+
+// TransTable 为无锁置换表，可在多个搜索协程间共享
+type TransTable struct {
+	slots []ttSlot
+	mask  uint64
+}
+
+// 创建大小约为sizeMB的置换表，槽位数取2的幂
+func NewTransTable(sizeMB int) *TransTable {
+	if sizeMB <= 0 {
+		sizeMB = DefaultTTSizeMB
+	}
+	n := uint64(1)
+	for n*2*16 <= uint64(sizeMB)<<20 {
+		n *= 2
+	}
+	return &TransTable{slots: make([]ttSlot, n), mask: n - 1}
+}
+
+// 查找局面key的记录
+func (t *TransTable) Probe(key uint64) (TTEntry, bool) {
+	slot := &t.slots[key&t.mask]
+	data := slot.data.Load()
+	if slot.check.Load()^data != key || data == 0 {
+		return TTEntry{}, false
+	}
+	return unpackEntry(data), true
+}
+
+// 保存局面key的记录，同一局面只用不浅于原记录的结果覆盖
+func (t *TransTable) Store(key uint64, e TTEntry) {
+	slot := &t.slots[key&t.mask]
+	old := slot.data.Load()
+	if slot.check.Load()^old == key && int(old>>32&0xff) > e.Depth {
+		return
+	}
+	data := packEntry(e)
+	slot.data.Store(data)
+	slot.check.Store(key ^ data)
+}
+
+// 清空置换表
+func (t *TransTable) Clear() {
+	for i := range t.slots {
+		t.slots[i].data.Store(0)
+		t.slots[i].check.Store(0)
+	}
+}
+
+const noMove = 1<<21 - 1
+
+func packEntry(e TTEntry) uint64 {
+	data := uint64(math.Float32bits(float32(e.Score)))
+	data |= uint64(min(max(e.Depth, 0), 0xff)) << 32
+	data |= uint64(e.Bound&3) << 40
+	move := uint64(noMove)
+	if e.HasMove {
+		move = packSquare(e.Move.From) | packSquare(e.Move.To)<<7 | packSquare(e.Move.Put)<<14
+	}
+	return data | move<<42
+}
+
+func unpackEntry(data uint64) TTEntry {
+	e := TTEntry{
+		Score: float64(math.Float32frombits(uint32(data))),
+		Depth: int(data >> 32 & 0xff),
+		Bound: Bound(data >> 40 & 3),
+	}
+	if move := data >> 42 & noMove; move != noMove {
+		e.HasMove = true
+		e.Move = AmazonMove{
+			From: unpackSquare(move),
+			To:   unpackSquare(move >> 7),
+			Put:  unpackSquare(move >> 14),
+		}
+	}
+	return e
+}
+
+func packSquare(p Position) uint64 {
+	return uint64(p.X*10 + p.Y)
+}
+
+func unpackSquare(v uint64) Position {
+	sq := int(v & 0x7f)
+	return Position{X: sq / 10, Y: sq % 10}
+}
+
+// 必胜/必败分值与到达层数有关，存入置换表时换算为相对当前节点的分值
+func scoreToTT(score float64, ply int) float64 {
+	switch {
+	case score >= MateScore-maxPly:
+		return score + float64(ply)
+	case score <= -MateScore+maxPly:
+		return score - float64(ply)
+	}
+	return score
+}
+
+func scoreFromTT(score float64, ply int) float64 {
+	switch {
+	case score >= MateScore-maxPly:
+		return score - float64(ply)
+	case score <= -MateScore+maxPly:
+		return score + float64(ply)
+	}
+	return score
+}
@@ -0,0 +1,125 @@
+package amazon
+
+import "testing"
+
+func TestTTEntryPacking(t *testing.T) {
+	corner := AmazonMove{From: Position{0, 0}, To: Position{9, 9}, Put: Position{0, 9}}
+	tests := []struct {
+		name string
+		in   TTEntry
+		want TTEntry // 与in相同时留空
+	}{
+		{"exact", TTEntry{Depth: 3, Bound: BoundExact, Score: 12.5, Move: corner, HasMove: true}, TTEntry{}},
+		{"lower", TTEntry{Depth: 1, Bound: BoundLower, Score: -7.25, Move: AmazonMove{Position{4, 7}, Position{5, 3}, Position{4, 7}}, HasMove: true}, TTEntry{}},
+		{"upper without move", TTEntry{Depth: 9, Bound: BoundUpper, Score: 0}, TTEntry{}},
+		{"mate", TTEntry{Depth: 255, Bound: BoundExact, Score: MateScore - 3, Move: corner, HasMove: true}, TTEntry{}},
+		{"mated", TTEntry{Depth: 0, Bound: BoundUpper, Score: -MateScore + 61}, TTEntry{}},
+		{"deep", TTEntry{Depth: 300, Score: 1}, TTEntry{Depth: 255, Score: 1}},
+		{"negative depth", TTEntry{Depth: -2, Score: 1}, TTEntry{Depth: 0, Score: 1}},
+		// 分值按float32保存
+		{"rounded score", TTEntry{Depth: 2, Score: 0.1}, TTEntry{Depth: 2, Score: float64(float32(0.1))}},
+	}
+	for _, tt := range tests {
+		want := tt.want
+		if want == (TTEntry{}) {
+			want = tt.in
+		}
+		data := packEntry(tt.in)
+		if data == 0 {
+			t.Errorf("%s: packed to 0, which marks an empty slot", tt.name)
+		}
+		if got := unpackEntry(data); got != want {
+			t.Errorf("%s: unpacked %+v, want %+v", tt.name, got, want)
+		}
+	}
+}
+
+func TestTTProbe(t *testing.T) {
+	tt := NewTransTable(1)
+	key := uint64(0x123456789abcdef)
+	if _, ok := tt.Probe(key); ok {
+		t.Fatal("hit in an empty table")
+	}
+	e := TTEntry{Depth: 4, Bound: BoundLower, Score: 3.5, Move: AmazonMove{Position{1, 2}, Position{3, 4}, Position{5, 6}}, HasMove: true}
+	tt.Store(key, e)
+	if got, ok := tt.Probe(key); !ok || got != e {
+		t.Fatalf("Probe = %+v, %v, want %+v", got, ok, e)
+	}
+	// 落在同一槽位的其他局面校验不通过
+	if _, ok := tt.Probe(key + tt.mask + 1); ok {
+		t.Error("hit for another key in the same slot")
+	}
+	// 只写了一半的记录（data与check不匹配）被丢弃
+	slot := &tt.slots[key&tt.mask]
+	slot.data.Store(packEntry(TTEntry{Depth: 1}))
+	if _, ok := tt.Probe(key); ok {
+		t.Error("hit for a torn entry")
+	}
+	tt.Store(key, e)
+	tt.Clear()
+	if _, ok := tt.Probe(key); ok {
+		t.Error("hit after Clear")
+	}
+}
+
+func TestTTStoreReplacement(t *testing.T) {
+	tt := NewTransTable(1)
+	key := uint64(42)
+	other := key + tt.mask + 1 // 与key同一槽位
+	shallow := TTEntry{Depth: 2, Score: 1}
+	deep := TTEntry{Depth: 5, Score: 2}
+	same := TTEntry{Depth: 5, Score: 3}
+	steps := []struct {
+		name  string
+		key   uint64
+		store TTEntry
+		probe uint64
+		want  TTEntry
+	}{
+		{"first store", key, shallow, key, shallow},
+		{"deeper replaces", key, deep, key, deep},
+		{"shallower kept out", key, shallow, key, deep},
+		{"equal depth replaces", key, same, key, same},
+		{"other key replaces", other, shallow, other, shallow},
+		{"original key evicted", other, shallow, key, TTEntry{}},
+	}
+	for _, s := range steps {
+		tt.Store(s.key, s.store)
+		got, ok := tt.Probe(s.probe)
+		if ok != (s.want != TTEntry{}) || got != s.want {
+			t.Fatalf("%s: Probe = %+v, %v, want %+v", s.name, got, ok, s.want)
+		}
+	}
+}
+
+func TestTTMateScore(t *testing.T) {
+	for ply := 0; ply < maxPly; ply++ {
+		for _, score := range []float64{0, 123.5, -80, MateScore - float64(ply) - 1, -MateScore + float64(ply) + 2} {
+			if got := scoreFromTT(scoreToTT(score, ply), ply); got != score {
+				t.Fatalf("ply %d: score %v round-trips to %v", ply, score, got)
+			}
+		}
+	}
+	// 在第3层的节点发现第5层必胜：存为距该节点2层，从第7层的另一条路径取出时为第9层必胜
+	stored := scoreToTT(MateScore-5, 3)
+	if stored != MateScore-2 {
+		t.Errorf("stored mate score %v, want %v", stored, MateScore-2)
+	}
+	if got := scoreFromTT(stored, 7); got != MateScore-9 {
+		t.Errorf("probed mate score %v, want %v", got, MateScore-9)
+	}
+	if got := scoreFromTT(scoreToTT(-MateScore+5, 3), 7); got != -MateScore+9 {
+		t.Errorf("probed mated score %v, want %v", got, -MateScore+9)
+	}
+	// 普通分值与层数无关
+	if got := scoreToTT(-512.25, 10); got != -512.25 {
+		t.Errorf("ordinary score stored as %v", got)
+	}
+	// 经过置换表的float32存储后必胜分值的层数仍然精确
+	tt := NewTransTable(1)
+	tt.Store(1, TTEntry{Depth: 1, Score: scoreToTT(MateScore-17, 4)})
+	e, _ := tt.Probe(1)
+	if got := scoreFromTT(e.Score, 6); got != MateScore-19 {
+		t.Errorf("mate score through the table %v, want %v", got, MateScore-19)
+	}
+}
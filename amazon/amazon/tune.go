@@ -0,0 +1,140 @@
+// 用带胜负标记的局面拟合评估参数（Texel方法），最小化按评估值预测的胜率相对对局结果的对数损失。
+package amazon
+
+import (
+	"math"
+	"slices"
+)
+
+// TuneSample 为一个训练局面
+type TuneSample struct {
+	Terms  EvalTerms // 局面的评估要素，黑方视角
+	Turn   int       // 局面所处的步数，决定使用哪个阶段的权重
+	Result float64   // 对局结果，黑胜为1，白胜为0
+}
+
+/*
+* 复盘一局棋，为每步之后的局面生成训练样本
+* 步数与对局中传给评估函数的一致：第i步走完后的局面在第i+1步时评估
+* 未分胜负的对局不产生样本
+ */
+func GameSamples(moves []AmazonMove, winner int) []TuneSample {
+	if winner != Black && winner != White {
+		return nil
+	}
+	result := 0.0
+	if winner == Black {
+		result = 1
+	}
+	board := NewBoard()
+	samples := make([]TuneSample, 0, len(moves))
+	for i, m := range moves {
+		board.Move(m)
+		samples = append(samples, TuneSample{Terms: board.CalculateEvalTerms(), Turn: i + 2, Result: result})
+	}
+	return samples
+}
+
+// TuneOptions 配置一次参数拟合
+type TuneOptions struct {
+	MaxPasses int                          // 最多的遍历轮数，0表示直到步长收敛
+	Log       func(pass int, loss float64) // 每轮结束时回调，可为nil
+}
+
+// 每个参数的初始步长，收敛到初始步长的1/minStepDiv时停止
+// 损失下降不超过minGain的改动不予保留，避免样本可分时权重无限放大
+const (
+	tuneBaseStep  = 1.0
+	tuneSlopeStep = 0.05
+	minStepDiv    = 16
+	minGain       = 1e-7
+)
+
+/*
+* Texel局部搜索
+* 先拟合把评估值映射为黑方胜率的缩放系数K：胜率 = 1/(1+exp(-K*评估值))
+* 再固定K，对每个阶段每个要素的Base和Slope依次尝试加减步长，损失下降则保留
+* 一轮中没有任何参数改进时步长减半，直到步长足够小或达到最大轮数
+* 阶段划分（Until）保持不变，返回拟合后的参数和最终损失
+ */
+func Tune(params EvalParams, samples []TuneSample, opts TuneOptions) (EvalParams, float64) {
+	p := EvalParams{Phases: slices.Clone(params.Phases)}
+	if len(samples) == 0 {
+		return p, 0
+	}
+	k := FitScale(&p, samples)
+
+	var values, steps []float64
+	var fields []*float64
+	for i := range p.Phases {
+		ph := &p.Phases[i]
+		for _, w := range []*Weight{&ph.QueenTerritory, &ph.KingTerritory, &ph.P1, &ph.P2, &ph.Mobility} {
+			fields = append(fields, &w.Base, &w.Slope)
+			steps = append(steps, tuneBaseStep, tuneSlopeStep)
+		}
+	}
+	for _, f := range fields {
+		values = append(values, *f)
+	}
+
+	best := TuneLoss(&p, samples, k)
+	for pass := 1; opts.MaxPasses == 0 || pass <= opts.MaxPasses; pass++ {
+		improved := false
+		for i, f := range fields {
+			for _, delta := range []float64{steps[i], -steps[i]} {
+				*f = values[i] + delta
+				if loss := TuneLoss(&p, samples, k); loss < best-minGain {
+					best, values[i] = loss, *f
+					improved = true
+					break
+				}
+				*f = values[i]
+			}
+		}
+		if opts.Log != nil {
+			opts.Log(pass, best)
+		}
+		if !improved {
+			if steps[0] < tuneBaseStep/minStepDiv {
+				break
+			}
+			for i := range steps {
+				steps[i] /= 2
+			}
+		}
+	}
+	return p, best
+}
+
+/*
+* TuneLoss 计算评估参数在样本上的平均对数损失（交叉熵）
+* 预测胜率p = sigmoid(k*评估值)，单个样本的损失为 -(r*log(p) + (1-r)*log(1-p))，r为对局结果
+* 按等价形式 softplus(z) - r*z 计算，z = k*评估值，避免p接近0或1时取对数溢出
+ */
+func TuneLoss(params *EvalParams, samples []TuneSample, k float64) float64 {
+	var sum float64
+	for _, s := range samples {
+		z := k * s.Terms.Dot(params.Weights(s.Turn))
+		sum += softplus(z) - s.Result*z
+	}
+	return sum / float64(len(samples))
+}
+
+// FitScale 在对数空间上三分查找使损失最小的缩放系数
+func FitScale(params *EvalParams, samples []TuneSample) float64 {
+	lo, hi := -6.0, 1.0
+	for i := 0; i < 60; i++ {
+		m1, m2 := lo+(hi-lo)/3, hi-(hi-lo)/3
+		if TuneLoss(params, samples, math.Pow(10, m1)) < TuneLoss(params, samples, math.Pow(10, m2)) {
+			hi = m2
+		} else {
+			lo = m1
+		}
+	}
+	return math.Pow(10, (lo+hi)/2)
+}
+
+// log(1+exp(x))，x很大时也不溢出
+func softplus(x float64) float64 {
+	return max(x, 0) + math.Log1p(math.Exp(-math.Abs(x)))
+}
@@ -0,0 +1,135 @@
+package amazon
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestTuneLowersLoss(t *testing.T) {
+	// 合成样本：胜负取决于queen领土与灵活度之和的符号，另有一成标签随机翻转，初始参数只看queen领土
+	r := rand.New(rand.NewSource(1))
+	samples := make([]TuneSample, 400)
+	for i := range samples {
+		terms := EvalTerms{QueenTerritory: r.NormFloat64(), Mobility: r.NormFloat64()}
+		result := 0.0
+		if terms.QueenTerritory+terms.Mobility > 0 != (r.Float64() < 0.1) {
+			result = 1
+		}
+		samples[i] = TuneSample{Terms: terms, Turn: 1 + r.Intn(40), Result: result}
+	}
+	start := EvalParams{Phases: []EvalPhase{{QueenTerritory: Weight{Base: 1}}}}
+	initial := TuneLoss(&start, samples, FitScale(&start, samples))
+
+	var passes []float64
+	tuned, loss := Tune(start, samples, TuneOptions{MaxPasses: 20, Log: func(pass int, loss float64) {
+		passes = append(passes, loss)
+	}})
+	if loss >= initial-0.05 {
+		t.Fatalf("Tune loss %.4f, initial %.4f", loss, initial)
+	}
+	for i := 1; i < len(passes); i++ {
+		if passes[i] > passes[i-1] {
+			t.Errorf("loss rose from %.6f to %.6f in pass %d", passes[i-1], passes[i], i+1)
+		}
+	}
+	if len(passes) == 0 || passes[len(passes)-1] != loss {
+		t.Errorf("Log reported %v, final loss %v", passes, loss)
+	}
+	if w := tuned.Phases[0].Mobility.At(20); w <= 0 {
+		t.Errorf("tuned mobility weight %v, want positive", w)
+	}
+	if start.Phases[0].QueenTerritory.Base != 1 || start.Phases[0].Mobility != (Weight{}) {
+		t.Error("Tune modified the initial parameters")
+	}
+}
+
+func TestTuneLoss(t *testing.T) {
+	params := EvalParams{Phases: []EvalPhase{{Mobility: Weight{Base: 1}}}}
+	samples := []TuneSample{
+		{Terms: EvalTerms{Mobility: 2}, Turn: 1, Result: 1},
+		{Terms: EvalTerms{Mobility: -1}, Turn: 1, Result: 1},
+		{Terms: EvalTerms{Mobility: 0.5}, Turn: 1, Result: 0},
+	}
+	// 按定义逐个计算对数损失
+	const k = 0.8
+	var want float64
+	for _, s := range samples {
+		p := 1 / (1 + math.Exp(-k*s.Terms.Mobility))
+		want -= s.Result*math.Log(p) + (1-s.Result)*math.Log(1-p)
+	}
+	want /= float64(len(samples))
+	if got := TuneLoss(&params, samples, k); math.Abs(got-want) > 1e-12 {
+		t.Errorf("TuneLoss = %v, want %v", got, want)
+	}
+
+	// 评估值极大时预测胜率在浮点数中等于0或1，损失仍然有限：判断正确时趋于0，判断错误时约为k*|评估值|
+	extreme := []TuneSample{{Terms: EvalTerms{Mobility: 1e4}, Turn: 1, Result: 1}}
+	if got := TuneLoss(&params, extreme, 1); got != 0 {
+		t.Errorf("confident correct prediction loss %v, want 0", got)
+	}
+	extreme[0].Result = 0
+	if got := TuneLoss(&params, extreme, 1); got != 1e4 {
+		t.Errorf("confident wrong prediction loss %v, want 1e4", got)
+	}
+}
+
+func TestParseRecord(t *testing.T) {
+	// 手写棋谱：黑棋d1走到d5放障碍到h5，白棋d10走到d6放障碍到h6
+	moves, winner, err := ParseRecord(strings.NewReader(
+		"#[AM][先手参赛队][后手参赛队][后手胜]2024.01.01 12:00;\r\n1 d1d5(h5)d10d6(h6)\r\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []AmazonMove{
+		{From: Position{9, 3}, To: Position{5, 3}, Put: Position{5, 7}},
+		{From: Position{0, 3}, To: Position{4, 3}, Put: Position{4, 7}},
+	}
+	if !slices.Equal(moves, want) || winner != White {
+		t.Errorf("ParseRecord = %v, %d, want %v, %d", moves, winner, want, White)
+	}
+
+	// 轮到白方时走黑棋
+	_, _, err = ParseRecord(strings.NewReader("1 d1d5(h5)d1d2(d3)\r\n"))
+	if !errors.Is(err, ErrNotOwnPiece) {
+		t.Errorf("ParseRecord with a move out of turn = %v, want %v", err, ErrNotOwnPiece)
+	}
+}
+
+func TestRecordRoundTrip(t *testing.T) {
+	r := rand.New(rand.NewSource(2))
+	for _, winner := range []int{Black, White, Empty} {
+		board, color := NewBoard(), Black
+		rec := GameRecord{Dir: t.TempDir()}
+		var moves []AmazonMove
+		for len(moves) < 30 {
+			legal := board.GenerateMoves(color, nil)
+			m := legal[r.Intn(len(legal))]
+			board.Move(m)
+			rec.AddMove(m)
+			moves = append(moves, m)
+			color = 3 - color
+		}
+		rec.Save(winner)
+		files, err := os.ReadDir(rec.Dir)
+		if err != nil || len(files) != 1 {
+			t.Fatalf("winner %d: record dir has %d files (%v)", winner, len(files), err)
+		}
+		got, gotWinner, err := LoadRecord(filepath.Join(rec.Dir, files[0].Name()))
+		if err != nil {
+			t.Fatalf("winner %d: %v", winner, err)
+		}
+		if !slices.Equal(got, moves) || gotWinner != winner {
+			t.Errorf("winner %d: loaded %d moves and winner %d, want %d moves", winner, len(got), gotWinner, len(moves))
+		}
+		// 复盘得到的样本数与着法数相同，未分胜负的对局没有样本
+		if n := len(GameSamples(got, gotWinner)); winner != Empty && n != len(moves) || winner == Empty && n != 0 {
+			t.Errorf("winner %d: %d samples", winner, n)
+		}
+	}
+}
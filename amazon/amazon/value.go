@@ -3,78 +3,41 @@ package amazon
 
 import "math"
 
-// CalculateKingMoves 计算并返回两个棋盘，分别表示黑白棋king走法的棋盘
+/*
+* 距离图
+* 以广度优先(位棋盘洪泛)计算双方到达每个空格所需的最少步数，可经过任意多步
+* 路径被障碍和所有棋子阻挡，不可达的格子以及非空格子取值为Unreachable
+ */
+
+// CalculateKingMoves 计算并返回黑白双方按king走法（每步走一格）的距离图
 func (b *AmazonBoard) CalculateKingMoves() (KingmoveBlack, KingmoveWhite AmazonBoard) {
-	// 初始化KingmoveBlack和KingmoveWhite为Empty
-	for x := range KingmoveBlack {
-		for y := range KingmoveBlack[x] {
-			KingmoveBlack[x][y] = Empty
-			KingmoveWhite[x][y] = Empty
-		}
-	}
-	// 遍历棋盘
-	for x := 0; x < 10; x++ {
-		for y := 0; y < 10; y++ {
-			if b[x][y] == Black || b[x][y] == White {
-				// 检查周围8个方向
-				for _, d := range dir {
-					newX, newY := x+d[0], y+d[1]
-					if newX >= 0 && newX < 10 && newY >= 0 && newY < 10 && b[newX][newY] == Empty {
-						if b[x][y] == Black {
-							KingmoveBlack[newX][newY] = 1 // 标记为可移动
-						} else {
-							KingmoveWhite[newX][newY] = 1 // 标记为可移动
-						}
-					}
-				}
-			}
-		}
-	}
-	return KingmoveBlack, KingmoveWhite
+	p := b.ToBitboard()
+	return distanceBoard(p.Distances(Black, KingReach)), distanceBoard(p.Distances(White, KingReach))
 }
 
+// CalculateQueenMoves 计算并返回黑白双方按queen走法（每步沿直线或斜线走任意格）的距离图
 func (b *AmazonBoard) CalculateQueenMoves() (QueenmoveBlack, QueenmoveWhite AmazonBoard) {
-	// 使用一个较大的数值来初始化棋盘，代表未被访问/不可达
-	const maxSteps = 100
-	for x := range QueenmoveBlack {
-		for y := range QueenmoveBlack[x] {
-			QueenmoveBlack[x][y] = maxSteps
-			QueenmoveWhite[x][y] = maxSteps
-		}
-	}
+	p := b.ToBitboard()
+	return distanceBoard(p.Distances(Black, QueenReach)), distanceBoard(p.Distances(White, QueenReach))
+}
 
+// 将按格子编号存放的距离转换为棋盘形式
+func distanceBoard(dist [100]int) (d AmazonBoard) {
 	for x := 0; x < 10; x++ {
 		for y := 0; y < 10; y++ {
-			currentPiece := b[x][y]
-			if currentPiece == Black || currentPiece == White {
-				for _, d := range dir {
-					steps := 1 // 从当前位置出发，所以步数从1开始计算
-					newX, newY := x+d[0], y+d[1]
-					for newX >= 0 && newX < 10 && newY >= 0 && newY < 10 && b[newX][newY] == Empty {
-						// 更新到达该位置的最小步数
-						if currentPiece == Black {
-							if steps < QueenmoveBlack[newX][newY] {
-								QueenmoveBlack[newX][newY] = steps
-							}
-						} else {
-							if steps < QueenmoveWhite[newX][newY] {
-								QueenmoveWhite[newX][newY] = steps
-							}
-						}
-						newX += d[0]
-						newY += d[1]
-						steps++
-					}
-				}
-			}
+			d[x][y] = dist[x*10+y]
 		}
 	}
-	return QueenmoveBlack, QueenmoveWhite
+	return d
 }
 
-// CalculateTerritoryValue 计算并返回双方基于king走法的领土值
+// CalculateKingTerritory 计算并返回双方基于king走法的领土值
 func (b *AmazonBoard) CalculateKingTerritory() (float64, float64) {
 	kingMovesBlack, kingMovesWhite := b.CalculateKingMoves()
+	return b.kingTerritory(kingMovesBlack, kingMovesWhite)
+}
+
+func (b *AmazonBoard) kingTerritory(kingMovesBlack, kingMovesWhite AmazonBoard) (float64, float64) {
 	var tkBlack, tkWhite float64
 
 	for x := 0; x < 10; x++ {
@@ -84,12 +47,14 @@ func (b *AmazonBoard) CalculateKingTerritory() (float64, float64) {
 				whiteSteps := kingMovesWhite[x][y]
 
 				switch {
-				case blackSteps == whiteSteps && blackSteps != 0:
-					tkBlack += 0.5
-					tkWhite += 0.5
-				case blackSteps != 0 && (whiteSteps == 0 || blackSteps < whiteSteps):
+				case blackSteps == whiteSteps:
+					if blackSteps != Unreachable { // 双方步数相同且都能到达
+						tkBlack += 0.5
+						tkWhite += 0.5
+					}
+				case blackSteps < whiteSteps:
 					tkBlack += 1
-				case whiteSteps != 0 && (blackSteps == 0 || whiteSteps < blackSteps):
+				default:
 					tkWhite += 1
 				}
 			}
@@ -99,9 +64,13 @@ func (b *AmazonBoard) CalculateKingTerritory() (float64, float64) {
 	return tkBlack, tkWhite
 }
 
-// CalculateTerritoryValue 计算并返回双方基于女王走法的领土值
+// CalculateQueenTerritory 计算并返回双方基于女王走法的领土值
 func (b *AmazonBoard) CalculateQueenTerritory() (float64, float64) {
 	queenMovesBlack, queenMovesWhite := b.CalculateQueenMoves()
+	return b.queenTerritory(queenMovesBlack, queenMovesWhite)
+}
+
+func (b *AmazonBoard) queenTerritory(queenMovesBlack, queenMovesWhite AmazonBoard) (float64, float64) {
 	var tqBlack, tqWhite float64
 
 	for x := 0; x < 10; x++ {
@@ -113,12 +82,12 @@ func (b *AmazonBoard) CalculateQueenTerritory() (float64, float64) {
 				// 比较双方的步数并计算领土值
 				switch {
 				case blackSteps == whiteSteps:
-					if blackSteps != 0 { // 如果双方步数相同且都能到达
+					if blackSteps != Unreachable { // 如果双方步数相同且都能到达
 						tqBlack += 0.5
 						tqWhite += 0.5
 					}
 				case blackSteps < whiteSteps:
-					if whiteSteps == 0 {
+					if whiteSteps == Unreachable {
 						// 如果白方到达不了
 						tqBlack += 2
 					} else {
@@ -126,7 +95,7 @@ func (b *AmazonBoard) CalculateQueenTerritory() (float64, float64) {
 						tqBlack += 1
 					}
 				case blackSteps > whiteSteps:
-					if blackSteps == 0 {
+					if blackSteps == Unreachable {
 						// 如果黑方到达不了
 						tqWhite += 2
 					} else {
@@ -142,82 +111,94 @@ func (b *AmazonBoard) CalculateQueenTerritory() (float64, float64) {
 }
 
 func (b *AmazonBoard) CalculateP1P2(queenMovesBlack, queenMovesWhite, kingMovesBlack, kingMovesWhite AmazonBoard) (float64, float64) {
-	var p1, p2 float64
+	p1Black, p1White, p2Black, p2White := b.p1p2(queenMovesBlack, queenMovesWhite, kingMovesBlack, kingMovesWhite)
+	return p1Black - p1White, p2Black - p2White
+}
 
-	// 计算P1，基于queen走法
+/*
+* 分别计算双方对P1、P2的贡献，P1、P2为双方贡献之差
+* P1基于queen距离：每个可达空格贡献2*2^-d，双方都可达时只有差值有意义
+* P2基于king距离之差：双方都可达的格子按(白步数-黑步数)/6截断到[-1,1]后计入领先的一方，只有一方可达的格子该方计1
+ */
+func (b *AmazonBoard) p1p2(queenMovesBlack, queenMovesWhite, kingMovesBlack, kingMovesWhite AmazonBoard) (p1Black, p1White, p2Black, p2White float64) {
 	for x := 0; x < 10; x++ {
 		for y := 0; y < 10; y++ {
-			if b[x][y] == Empty { // 只考虑空格
-				blackSteps := float64(queenMovesBlack[x][y])
-				whiteSteps := float64(queenMovesWhite[x][y])
-
-				if blackSteps != 100 && whiteSteps != 100 {
-					p1 += math.Pow(2.0, -blackSteps) - math.Pow(2.0, -whiteSteps)
-				} else if blackSteps != 100 {
-					p1 += math.Pow(2.0, -blackSteps)
-				} else if whiteSteps != 100 {
-					p1 -= math.Pow(2.0, -whiteSteps)
-				}
+			if b[x][y] != Empty { // 只考虑空格
+				continue
+			}
+			if steps := queenMovesBlack[x][y]; steps != Unreachable {
+				p1Black += 2 * math.Pow(2.0, -float64(steps))
+			}
+			if steps := queenMovesWhite[x][y]; steps != Unreachable {
+				p1White += 2 * math.Pow(2.0, -float64(steps))
 			}
-		}
-	}
-	p1 *= 2
-
-	// 计算P2，基于king走法
-	for x := 0; x < 10; x++ {
-		for y := 0; y < 10; y++ {
-			if b[x][y] == Empty {
-				blackControl := kingMovesBlack[x][y]
-				whiteControl := kingMovesWhite[x][y]
 
-				if blackControl == 1 && whiteControl == 1 {
-					// 如果双方都可以控制这个格子，使用min和max函数处理差值
-					diff := float64(whiteControl - blackControl)
-					p2 += math.Min(1.0, math.Max(-1.0, diff/6.0))
-				} else if blackControl == 1 {
-					p2 += 1
-				} else if whiteControl == 1 {
-					p2 -= 1
+			blackSteps := kingMovesBlack[x][y]
+			whiteSteps := kingMovesWhite[x][y]
+			switch {
+			case blackSteps != Unreachable && whiteSteps != Unreachable:
+				// 如果双方都可以到达这个格子，使用min和max函数处理差值
+				diff := math.Min(1.0, math.Max(-1.0, float64(whiteSteps-blackSteps)/6.0))
+				if diff > 0 {
+					p2Black += diff
+				} else {
+					p2White -= diff
 				}
-				// 注意，如果都不能到达该格子，则不计分
+			case blackSteps != Unreachable:
+				p2Black += 1
+			case whiteSteps != Unreachable:
+				p2White += 1
 			}
+			// 注意，如果都不能到达该格子，则不计分
 		}
 	}
-
-	return p1, p2
+	return p1Black, p1White, p2Black, p2White
 }
 
-// 灵活度
+// 灵活度，黑方为正、白方为负，取值在[-1,1]之间
 func (b *AmazonBoard) CalculateMobility() float64 {
 	queenMovesBlack, queenMovesWhite := b.CalculateQueenMoves()
 	kingMovesBlack, kingMovesWhite := b.CalculateKingMoves()
-	var mobilityBlack, mobilityWhite float64
+	return b.mobility(queenMovesBlack, queenMovesWhite, kingMovesBlack, kingMovesWhite)
+}
 
+/*
+* 一方的灵活度为其一步queen走法可达的每个空格的灵活度之和
+* 每个空格的灵活度为其相邻空格数，再除以该方到达该格的king距离，越远的格子权重越低
+ */
+func (b *AmazonBoard) mobility(queenMovesBlack, queenMovesWhite, kingMovesBlack, kingMovesWhite AmazonBoard) float64 {
+	return mobilityBalance(b.mobilitySides(queenMovesBlack, queenMovesWhite, kingMovesBlack, kingMovesWhite))
+}
+
+// 分别计算双方的灵活度
+func (b *AmazonBoard) mobilitySides(queenMovesBlack, queenMovesWhite, kingMovesBlack, kingMovesWhite AmazonBoard) (mobilityBlack, mobilityWhite float64) {
 	for x := 0; x < 10; x++ {
 		for y := 0; y < 10; y++ {
 			if b[x][y] == Empty {
 				mobility := float64(b.calculateMobilityForCell(x, y)) // 确保使用float64进行计算
-				// 为避免除以零的情况，确保kingMoves中的值不是0，如果是0，意味着棋子无法到达，不应该计入灵活度
-				if queenMovesBlack[x][y] != 100 && kingMovesBlack[x][y] != 0 {
+				// 一步queen走法可达的格子king距离至少为1，不会除以零
+				if queenMovesBlack[x][y] == 1 {
 					mobilityBlack += mobility / float64(kingMovesBlack[x][y])
 				}
-				if queenMovesWhite[x][y] != 100 && kingMovesWhite[x][y] != 0 {
+				if queenMovesWhite[x][y] == 1 {
 					mobilityWhite += mobility / float64(kingMovesWhite[x][y])
 				}
 			}
 		}
 	}
+	return mobilityBlack, mobilityWhite
+}
 
-	// 为避免除以零的错误，对方的灵活度值加上一个小数，确保除数不为零
-	if mobilityWhite == 0 {
-		mobilityWhite += 0.00001
+/*
+* 双方灵活度的归一化差值 (黑-白)/(黑+白)
+* 交换双方时取值恰好变号，双方都无灵活度时为0
+* 原先的比值 黑/白 在均势时为1而不是0，交换双方也不会变号，会让评估偏向某一方
+ */
+func mobilityBalance(mobilityBlack, mobilityWhite float64) float64 {
+	if sum := mobilityBlack + mobilityWhite; sum > 0 {
+		return (mobilityBlack - mobilityWhite) / sum
 	}
-	if mobilityBlack == 0 {
-		mobilityBlack += 0.00001
-	}
-
-	// 返回双方灵活度值的比值
-	return mobilityBlack / mobilityWhite
+	return 0
 }
 
 // CalculateMobilityForCell 计算单个空格的灵活度值
@@ -232,31 +213,62 @@ func (b *AmazonBoard) calculateMobilityForCell(x, y int) int {
 	return mobility
 }
 
-// CalculateEvaluationValue 作为 AmazonBoard 的方法
-func (b *AmazonBoard) CalculateEvaluationValue(turnID int, isBlackTurn bool) float64 {
-	// 首先，计算tq, tk, p1, p2, 和mobility的值
-	tqBlack, tqWhite := b.CalculateQueenTerritory()
-	tkBlack, tkWhite := b.CalculateKingTerritory()
+// EvalTerms 为评估函数的各要素，均以黑方视角计算，交换双方时各要素变号
+// 同一结构也用来存放各要素对应的权重
+type EvalTerms struct {
+	QueenTerritory float64 `json:"queen_territory"` // tq：queen距离领土差
+	KingTerritory  float64 `json:"king_territory"`  // tk：king距离领土差
+	P1             float64 `json:"p1"`              // queen距离的位置特征
+	P2             float64 `json:"p2"`              // king距离的位置特征
+	Mobility       float64 `json:"mobility"`        // 灵活度
+}
+
+// 各要素与对应权重的加权和
+func (t EvalTerms) Dot(w EvalTerms) float64 {
+	return t.QueenTerritory*w.QueenTerritory + t.KingTerritory*w.KingTerritory +
+		t.P1*w.P1 + t.P2*w.P2 + t.Mobility*w.Mobility
+}
+
+// CalculateEvalTerms 计算评估函数的各要素，距离图只计算一次，各要素共用
+func (b *AmazonBoard) CalculateEvalTerms() EvalTerms {
+	return combineTerms(b.evalSides())
+}
+
+// 分别计算双方在各要素上的原始值
+func (b *AmazonBoard) evalSides() (black, white EvalTerms) {
 	queenMovesBlack, queenMovesWhite := b.CalculateQueenMoves()
 	kingMovesBlack, kingMovesWhite := b.CalculateKingMoves()
-	p1, p2 := b.CalculateP1P2(queenMovesBlack, queenMovesWhite, kingMovesBlack, kingMovesWhite)
-	mobility := b.CalculateMobility()
+	black.QueenTerritory, white.QueenTerritory = b.queenTerritory(queenMovesBlack, queenMovesWhite)
+	black.KingTerritory, white.KingTerritory = b.kingTerritory(kingMovesBlack, kingMovesWhite)
+	black.P1, white.P1, black.P2, white.P2 = b.p1p2(queenMovesBlack, queenMovesWhite, kingMovesBlack, kingMovesWhite)
+	black.Mobility, white.Mobility = b.mobilitySides(queenMovesBlack, queenMovesWhite, kingMovesBlack, kingMovesWhite)
+	return black, white
+}
 
-	// 根据turnID调整每个要素的权重
-	var k1, k2, k3, k4, k5 float64
-	if turnID < 17 {
-		k1 = 2 * (32 + float64(turnID)/2)
-		k2 = 1 * (32 - 1.8*float64(turnID)/2)
-		k3 = 1 * (32 - 1.8*float64(turnID)/2)
-		k4 = 2 * (32 - 2.0*float64(turnID)/2)
-		k5 = 0.5 * (32 - 1.8*float64(turnID)/2)
-	} else {
-		k1 = 5
-		k2, k3, k4, k5 = 0, 0, 0, 0
+// 由双方的原始值得到评估要素：灵活度取归一化差值，其余取差值
+func combineTerms(black, white EvalTerms) EvalTerms {
+	return EvalTerms{
+		QueenTerritory: black.QueenTerritory - white.QueenTerritory,
+		KingTerritory:  black.KingTerritory - white.KingTerritory,
+		P1:             black.P1 - white.P1,
+		P2:             black.P2 - white.P2,
+		Mobility:       mobilityBalance(black.Mobility, white.Mobility),
 	}
+}
 
-	// 计算整个棋局的评估值
-	value := k1*(tqBlack-tqWhite) + k2*(tkBlack-tkWhite) + k3*p1 + k4*p2 + k5*mobility
+// CalculateEvaluationValue 使用默认参数以color一方视角计算评估值
+func (b *AmazonBoard) CalculateEvaluationValue(turnID int, color int) float64 {
+	return b.EvaluateWith(&DefaultEvalParams, turnID, color)
+}
 
+/*
+* EvaluateWith 使用指定的评估参数计算评估值，权重按turnID所处阶段选取
+* 分值以color一方视角给出（负极大值约定）：对color有利为正，交换视角时变号
+ */
+func (b *AmazonBoard) EvaluateWith(params *EvalParams, turnID int, color int) float64 {
+	value := b.CalculateEvalTerms().Dot(params.Weights(turnID))
+	if color == White {
+		return -value
+	}
 	return value
 }
@@ -0,0 +1,82 @@
+package amazon
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// 交换双方颜色并上下翻转棋盘，得到对另一方完全等价的局面
+func mirrorColors(b *AmazonBoard) *AmazonBoard {
+	m := &AmazonBoard{}
+	for x := 0; x < 10; x++ {
+		for y := 0; y < 10; y++ {
+			v := b[x][y]
+			if v == Black || v == White {
+				v = 3 - v
+			}
+			m[9-x][y] = v
+		}
+	}
+	return m
+}
+
+func almostEqual(a, b float64) bool {
+	return math.Abs(a-b) <= 1e-9*math.Max(1, math.Max(math.Abs(a), math.Abs(b)))
+}
+
+func TestEvaluationSideRelative(t *testing.T) {
+	r := rand.New(rand.NewSource(3))
+	for i := 0; i < 50; i++ {
+		b, _ := randomPosition(r, r.Intn(60))
+		turn := 1 + r.Intn(40)
+		black := b.CalculateEvaluationValue(turn, Black)
+		white := b.CalculateEvaluationValue(turn, White)
+		if !almostEqual(black, -white) {
+			t.Fatalf("position %d: eval black %v white %v, want negation", i, black, white)
+		}
+	}
+}
+
+func TestEvaluationColorMirror(t *testing.T) {
+	r := rand.New(rand.NewSource(4))
+	for i := 0; i < 50; i++ {
+		b, color := randomPosition(r, r.Intn(60))
+		m := mirrorColors(b)
+		turn := 1 + r.Intn(40)
+
+		orig, mirrored := b.CalculateEvalTerms(), m.CalculateEvalTerms()
+		for _, pair := range [][2]float64{
+			{orig.QueenTerritory, mirrored.QueenTerritory},
+			{orig.KingTerritory, mirrored.KingTerritory},
+			{orig.P1, mirrored.P1},
+			{orig.P2, mirrored.P2},
+			{orig.Mobility, mirrored.Mobility},
+		} {
+			if !almostEqual(pair[0], -pair[1]) {
+				t.Fatalf("position %d: terms %+v and mirrored %+v do not negate", i, orig, mirrored)
+			}
+		}
+
+		// 行棋方视角的分值在镜像局面中由另一方得到
+		if got, want := m.CalculateEvaluationValue(turn, 3-color), b.CalculateEvaluationValue(turn, color); !almostEqual(got, want) {
+			t.Fatalf("position %d: mirrored eval %v, want %v", i, got, want)
+		}
+		if got, want := m.CalculateEvaluationValue(turn, Black), -b.CalculateEvaluationValue(turn, Black); !almostEqual(got, want) {
+			t.Fatalf("position %d: mirrored black eval %v, want %v", i, got, want)
+		}
+	}
+}
+
+func TestSearchColorMirror(t *testing.T) {
+	r := rand.New(rand.NewSource(5))
+	for i := 0; i < 5; i++ {
+		b, color := randomPosition(r, 20+r.Intn(30))
+		m := mirrorColors(b)
+		orig, ok1 := b.Search(color, SearchOptions{Step: 10, MaxDepth: 1})
+		mirrored, ok2 := m.Search(3-color, SearchOptions{Step: 10, MaxDepth: 1})
+		if ok1 != ok2 || !almostEqual(orig.Score, mirrored.Score) {
+			t.Fatalf("position %d: search score %v, mirrored %v", i, orig.Score, mirrored.Score)
+		}
+	}
+}
@@ -1,5 +1,5 @@
 module tamazon
 
-go 1.22.1
+go 1.23
 
 require github.com/tongque0/gotack v1.4.3
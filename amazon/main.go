@@ -1,118 +1,145 @@
 package main
 
 import (
-	"bufio"
+	"context"
+	"errors"
+	"flag"
 	"fmt"
+	"io/fs"
 	"os"
-	"strings"
+	"path/filepath"
+	"runtime"
 	"tamazon/amazon"
-
-	"github.com/tongque0/gotack"
+	"tamazon/protocol"
 )
 
 const INF = 0x3f3f3f3f  // 表示"无穷大"的常量，常用于最大最小值初始化
 const Name = "MTackTao" // 程序名称
 
+// 引擎版本（qtack/stack/mtack），编译时由makefile通过-ldflags "-X main.searchMode=..."设置
+var searchMode string
+
 var (
-	line  string              // 存储输入的行
-	step  int                 // 当前步数
-	board *amazon.AmazonBoard // 棋盘
-	color int                 // 当前颜色
+	gameTime   = flag.Duration("clock", amazon.DefaultGameTime, "本方整局总用时")
+	evalConfig = flag.String("eval-config", "", "评估参数文件，默认为可执行文件同目录下的eval.json（各版本为eval-<版本>.json）")
+	evalKind   = flag.String("eval", "hand", "评估器：hand为手工评估函数，nn为估值网络")
+	nnPath     = flag.String("nn", "", "估值网络权重文件，默认为可执行文件同目录下的nn.json")
+	algorithm  = flag.String("search", defaultAlgorithm(), "搜索算法：ab为迭代加深Alpha-Beta，mcts为蒙特卡洛树搜索")
+	threads    = flag.Int("threads", runtime.NumCPU(), "搜索线程数，Alpha-Beta为Lazy SMP，蒙特卡洛树搜索见-mcts-parallel")
+	ponder     = flag.Bool("ponder", true, "对手思考期间是否在后台搜索预测的应着")
+	parallel   = flag.String("mcts-parallel", "tree", "多线程蒙特卡洛树搜索的方式：tree为共享一棵树（虚拟失败），root为各线程独立建树后合并")
 )
 
+// 各版本默认的搜索算法：MTack为UCT版本，其余为Alpha-Beta
+func defaultAlgorithm() string {
+	if searchMode == "mtack" {
+		return protocol.AlgorithmMCTS
+	}
+	return protocol.AlgorithmAlphaBeta
+}
+
+// 可执行文件所在目录下的文件，无法确定可执行文件位置时返回空串
+func besideExecutable(name string) string {
+	exe, err := os.Executable()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(filepath.Dir(exe), name)
+}
+
 /*
- * main
- * 通过命令行输入实现前端UI交互协议
- * 输入"new black"或"new white"开始新游戏
- * 输入"move A1B2C3"进行移动，格式为"move from to put"
- * 输入"end"保存游戏记录
+* 加载评估参数
+* 未指定文件时在可执行文件所在目录查找，找不到则静默使用默认参数
+* 文件存在但无效时给出警告并使用默认参数
  */
-func main() {
-	fmt.Printf("-------------欢迎使用%s-----------------\n", Name)
-	sc := bufio.NewScanner(os.Stdin)
-	for sc.Scan() {
-		line = sc.Text()
-		if line == "name?" {
-			fmt.Printf("name %s\n", Name)
-		} else if line == "quit" {
-			os.Exit(0)
-		} else if strings.HasPrefix(line, "new") {
-			step = 1
-			words := strings.Split(line, " ")
-			board = amazon.NewBoard()
-			if words[1] == "black" {
-				color = amazon.Black
-				runSearch()
-			} else {
-				color = amazon.White
-			}
-		} else if strings.HasPrefix(line, "move") {
-			words := strings.Split(line, " ")
-			move := words[1]
-			board[move[3]-'A'][move[2]-'A'] = board[move[1]-'A'][move[0]-'A']
-			board[move[1]-'A'][move[0]-'A'] = amazon.Empty
-			board[move[5]-'A'][move[4]-'A'] = amazon.Arrow
-			step++
-			if !board.IsGameOver() {
-				runSearch()
-			}
-		} else if line == "end" {
-			amazon.Save()
-			continue
-		} else {
-			amazon.Save()
-			continue
+func loadEvalParams() *amazon.EvalParams {
+	path := *evalConfig
+	if path == "" {
+		name := "eval.json"
+		if searchMode != "" {
+			name = "eval-" + searchMode + ".json"
+		}
+		if path = besideExecutable(name); path == "" {
+			return &amazon.DefaultEvalParams
+		}
+	}
+	params, err := amazon.LoadEvalParams(path)
+	if err != nil {
+		if *evalConfig != "" || !errors.Is(err, fs.ErrNotExist) {
+			fmt.Fprintf(os.Stderr, "使用默认评估参数: %v\n", err)
 		}
+		return &amazon.DefaultEvalParams
 	}
+	return &params
+}
+
+// 按-eval选择搜索使用的评估器，手工评估函数返回nil（由会话按评估参数构造）
+func loadEvaluator() (amazon.Evaluator, error) {
+	switch *evalKind {
+	case "hand":
+		return nil, nil
+	case "nn":
+		path := *nnPath
+		if path == "" {
+			path = besideExecutable("nn.json")
+		}
+		n, err := amazon.LoadNetwork(path)
+		if err != nil {
+			return nil, err
+		}
+		return n, nil
+	}
+	return nil, fmt.Errorf("unknown evaluator %q", *evalKind)
 }
 
 /*
- * runSearch
- * 运行搜索算法，寻找最佳移动
+ * main
+ * 通过标准输入输出与前端UI平台交互，协议处理见 protocol.Session
+ * 第一个参数为tune时进入参数拟合模式，见 runTune；为selfplay时进入自对弈模式，见 runSelfPlay
  */
-
-func runSearch() {
-	var IsMaxPlayer = true
-	var e *gotack.Evaluator
-	if color == 2 { // 白方
-		IsMaxPlayer = false
+func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "tune":
+			os.Exit(runTune(os.Args[2:]))
+		case "selfplay":
+			os.Exit(runSelfPlay(os.Args[2:]))
+		}
+	}
+	flag.Parse()
+	fmt.Printf("-------------欢迎使用%s-----------------\n", Name)
+	session := protocol.NewSession(Name, os.Stdin, os.Stdout)
+	session.Detail = true // 详细输出
+	session.GameTime = *gameTime
+	session.Params = loadEvalParams()
+	eval, err := loadEvaluator()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading evaluator: %v\n", err)
+		os.Exit(1)
 	}
-	// 根据步数动态设置搜索深度
-	var searchDetph int
-	switch {
-	case step < 23:
-		searchDetph = 2
-	case step < 50:
-		searchDetph = 3
-	case step < 70:
-		searchDetph = 4
+	session.Eval = eval
+	session.Threads = *threads
+	session.Ponder = *ponder
+	switch *algorithm {
+	case protocol.AlgorithmAlphaBeta, protocol.AlgorithmMCTS:
+		session.Algorithm = *algorithm
 	default:
-		searchDetph = 5
+		fmt.Fprintf(os.Stderr, "Unknown search algorithm %q\n", *algorithm)
+		os.Exit(1)
 	}
-
-	// 创建评估器
-	e = gotack.NewEvaluator(
-		gotack.AlphaBeta, // 使用Alpha-Beta剪枝算法
-		gotack.NewEvaluatorOptions(
-			gotack.WithBoard(board),             // 当前棋盘
-			gotack.WithDepth(searchDetph),       // 搜索深度
-			gotack.WithIsMaxPlayer(IsMaxPlayer), //最大玩家
-			gotack.WithStep(step),               // 当前步数
-			gotack.WithIsDetail(true),           // 详细输出
-		),
-	)
-	// 获取最佳移动
-	move := e.GetBestMove()
-	m, ok := move[0].(amazon.AmazonMove)
-	if !ok {
-		return
+	mcts := amazon.DefaultMCTSOptions
+	switch *parallel {
+	case "tree":
+		mcts.Parallel = amazon.TreeParallel
+	case "root":
+		mcts.Parallel = amazon.RootParallel
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown MCTS parallel mode %q\n", *parallel)
+		os.Exit(1)
+	}
+	session.MCTS = &mcts
+	if err := session.Run(context.Background()); err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading input: %v\n", err)
+		os.Exit(1)
 	}
-	// 执行最佳移动
-	board.Move(move[0])
-	// 输出移动信息
-	fmt.Printf("move %c%c%c%c%c%c\n", m.From.Y+'A', m.From.X+'A', m.To.Y+'A', m.To.X+'A', m.Put.Y+'A', m.Put.X+'A')
-	// 记录游戏
-	amazon.AddRecord(m.From.Y+'a', 10-m.From.X, m.To.Y+'a', 10-m.To.X, m.Put.Y+'a', 10-m.Put.X)
-	// 更新步数
-	step++
 }
@@ -0,0 +1,140 @@
+// 在对手思考期间后台搜索预测的对手应着，对手着法与预测一致时沿用搜索结果。
+package protocol
+
+import (
+	"fmt"
+	"sync/atomic"
+	"tamazon/amazon"
+	"time"
+)
+
+// 一次后台思考，done关闭后结果字段可读
+type ponder struct {
+	guess    amazon.AmazonMove // 预测的对手着法，hasGuess为false时在对手视角搜索当前局面
+	hasGuess bool
+	stop     atomic.Bool
+	done     chan struct{}
+	start    time.Time // 后台搜索开始的时间
+
+	move amazon.AmazonMove // 对预测局面搜出的本方着法
+	ok   bool
+	info string // 搜索信息，命中时输出
+}
+
+/*
+* 本方着法发出后开始后台思考
+* Alpha-Beta能从置换表取出对手的预期应着时，在走完该应着的局面上为本方搜索；
+* 取不到时在对手视角搜索当前局面，为对手的各种应着预热置换表
+* 蒙特卡洛树搜索在保留的搜索树上以对手视角继续搜索，对手走出任何着法后都沿用对应子树
+* 后台搜索只使用棋盘副本、置换表和搜索树，不向平台输出
+* 除停止信号外另以本方剩余用时为上限，对手迟迟不走或会话不再停止思考时也不会一直占用CPU
+ */
+func (s *Session) startPonder() {
+	if !s.Ponder || s.board.IsGameOver() {
+		return
+	}
+	p := &ponder{done: make(chan struct{})}
+	board, color, step := *s.board, 3-s.color, s.step
+	limit := s.clock.Remaining // 后台搜索的结果最多用于本方一步，用不到比剩余用时更多的时间
+
+	// 搜索设置在此取好，后台协程不再访问会话
+	var search func() (amazon.AmazonMove, bool, string)
+	if s.Algorithm == AlgorithmMCTS {
+		opts := s.mctsOptions()
+		opts.Step, opts.Stop = step, &p.stop
+		opts.Budget, opts.TimeAlloc = limit, false
+		if s.mcts == nil {
+			s.mcts = amazon.NewMCTS(opts)
+		} else {
+			s.mcts.SetOptions(opts)
+		}
+		m := s.mcts
+		search = func() (amazon.AmazonMove, bool, string) {
+			result, ok := m.Search(&board, color)
+			return result.Move, ok, ""
+		}
+	} else {
+		if s.tt == nil {
+			return
+		}
+		if p.guess, p.hasGuess = s.tt.BestMove(&board, color); p.hasGuess {
+			board.Move(p.guess)
+			color, step = s.color, step+1
+			if !board.HasMoves(color) {
+				return
+			}
+		}
+		opts := amazon.SearchOptions{
+			Step:     step,
+			Deadline: time.Now().Add(limit),
+			TT:       s.tt,
+			Threads:  s.Threads,
+			Eval:     s.evaluator(),
+			Stop:     &p.stop,
+		}
+		search = func() (amazon.AmazonMove, bool, string) {
+			result, ok := board.Search(color, opts)
+			return result.Move, ok, fmt.Sprintf("depth %d score %.2f nodes %d time %v move %s",
+				result.Depth, result.Score, result.Nodes, result.Elapsed.Round(time.Millisecond), result.Move.Notation())
+		}
+	}
+	if s.Detail {
+		if p.hasGuess {
+			fmt.Fprintf(s.out, "info ponder %s\n", p.guess.Notation())
+		} else {
+			fmt.Fprintln(s.out, "info ponder any")
+		}
+	}
+	s.pondering = p
+	p.start = time.Now()
+	go func() {
+		defer close(p.done)
+		p.move, p.ok, p.info = search()
+	}()
+}
+
+// 停止后台思考并等待其结束
+func (s *Session) stopPonder() {
+	if s.pondering == nil {
+		return
+	}
+	s.pondering.stop.Store(true)
+	<-s.pondering.done
+	s.pondering = nil
+}
+
+/*
+* 收到对手着法m后处理后台思考
+* 预测命中时后台搜索已在对手思考期间运行，只让它补足本步时间预算中尚未用掉的部分，然后走出其结果，返回true
+* 预测落空、没有预测、局面需要残局填充或精确求解时停止后台思考并返回false，
+* 由调用方重新搜索（置换表已预热，搜索树已积累对手各应着的统计）
+* regions为走完m后的区域划分
+ */
+func (s *Session) ponderHit(m amazon.AmazonMove, regions amazon.Partition) bool {
+	p := s.pondering
+	if p == nil || !p.hasGuess || p.guess != m || regions.Solvable() {
+		s.stopPonder()
+		return false
+	}
+	if _, ok := regions.FillingMove(s.color); ok {
+		s.stopPonder()
+		return false
+	}
+	start := time.Now()
+	if wait := s.clock.Budget(s.board) - start.Sub(p.start); wait > 0 {
+		select {
+		case <-p.done:
+		case <-time.After(wait):
+		}
+	}
+	s.stopPonder()
+	s.clock.Spend(time.Since(start)) // 收到对手着法之后的等待计入本方用时，未命中结果时也是如此
+	if !p.ok {
+		return false
+	}
+	if s.Detail {
+		fmt.Fprintf(s.out, "info ponderhit %s\n", p.info)
+	}
+	s.play(p.move)
+	return true
+}
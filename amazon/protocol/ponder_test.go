@@ -0,0 +1,218 @@
+package protocol
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"tamazon/amazon"
+	"testing"
+	"time"
+)
+
+// 逐条发送命令并等待应答的测试平台，会话在后台运行
+type driver struct {
+	t     *testing.T
+	in    *io.PipeWriter
+	lines chan string
+	done  chan error
+}
+
+func startDriver(t *testing.T, s *Session) *driver {
+	inR, inW := io.Pipe()
+	outR, outW := io.Pipe()
+	s.in, s.out = inR, outW
+	d := &driver{t: t, in: inW, lines: make(chan string, 64), done: make(chan error, 1)}
+	go func() {
+		defer close(d.lines)
+		sc := bufio.NewScanner(outR)
+		for sc.Scan() {
+			d.lines <- sc.Text()
+		}
+	}()
+	go func() {
+		err := s.Run(context.Background())
+		outW.Close()
+		d.done <- err
+	}()
+	return d
+}
+
+func (d *driver) send(cmd string) {
+	d.t.Helper()
+	if _, err := io.WriteString(d.in, cmd+"\n"); err != nil {
+		d.t.Fatalf("send %q: %v", cmd, err)
+	}
+}
+
+// 读取输出直到以prefix开头的一行，返回该行及之前跳过的各行
+func (d *driver) expect(prefix string) (string, []string) {
+	d.t.Helper()
+	var skipped []string
+	timeout := time.After(30 * time.Second)
+	for {
+		select {
+		case line, ok := <-d.lines:
+			if !ok {
+				d.t.Fatalf("output closed while waiting for %q, got %q", prefix, skipped)
+			}
+			if strings.HasPrefix(line, prefix) {
+				return line, skipped
+			}
+			skipped = append(skipped, line)
+		case <-timeout:
+			d.t.Fatalf("timed out waiting for %q, got %q", prefix, skipped)
+		}
+	}
+}
+
+// 读取一条"move"应答并在棋盘上执行
+func (d *driver) expectMove(b *amazon.AmazonBoard, color int) []string {
+	d.t.Helper()
+	line, skipped := d.expect("move ")
+	m, err := b.ParseLegalMove(strings.TrimPrefix(line, "move "), color)
+	if err != nil {
+		d.t.Fatalf("engine played %q: %v", line, err)
+	}
+	b.Move(m)
+	return skipped
+}
+
+func (d *driver) quit() {
+	d.t.Helper()
+	d.send("quit")
+	for range d.lines {
+	}
+	if err := <-d.done; err != nil {
+		d.t.Errorf("Run: %v", err)
+	}
+}
+
+func newPonderSession(t *testing.T) *Session {
+	s := NewSession("test", nil, nil)
+	s.Ponder = true
+	s.Detail = true
+	s.Eval = flatEval{}           // 评估足够便宜，-race下也能在预算内完成前几轮迭代
+	s.GameTime = 60 * time.Second // 每步约一秒
+	s.Record.Dir = t.TempDir()
+	return s
+}
+
+func hasPrefix(lines []string, prefix string) bool {
+	for _, l := range lines {
+		if strings.HasPrefix(l, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestPonderHit(t *testing.T) {
+	d := startDriver(t, newPonderSession(t))
+	board := amazon.NewBoard()
+	d.send("new black")
+	d.expectMove(board, amazon.Black)
+
+	// 按预测走出对手着法，引擎应沿用后台搜索的结果
+	line, _ := d.expect("info ponder ")
+	guess := strings.TrimPrefix(line, "info ponder ")
+	if guess == "any" {
+		t.Fatal("no ponder guess after a full search")
+	}
+	m, err := board.ParseLegalMove(guess, amazon.White)
+	if err != nil {
+		t.Fatalf("ponder guess %q: %v", guess, err)
+	}
+	board.Move(m)
+	d.send("move " + guess)
+	skipped := d.expectMove(board, amazon.Black)
+	if !hasPrefix(skipped, "info ponderhit ") {
+		t.Errorf("no ponderhit before the reply, got %q", skipped)
+	}
+	if hasPrefix(skipped, "info depth ") {
+		t.Errorf("searched again after a ponder hit: %q", skipped)
+	}
+	d.quit()
+}
+
+func TestPonderHitAfterLongThink(t *testing.T) {
+	d := startDriver(t, newPonderSession(t))
+	board := amazon.NewBoard()
+	d.send("new black")
+	d.expectMove(board, amazon.Black)
+	line, _ := d.expect("info ponder ")
+	guess := strings.TrimPrefix(line, "info ponder ")
+	m, err := board.ParseLegalMove(guess, amazon.White)
+	if err != nil {
+		t.Fatalf("ponder guess %q: %v", guess, err)
+	}
+
+	// 对手思考的时间超过本步约一秒的预算，后台搜索已经用够，命中后应立即走棋而不再等一整步
+	time.Sleep(2 * time.Second)
+	board.Move(m)
+	start := time.Now()
+	d.send("move " + guess)
+	skipped := d.expectMove(board, amazon.Black)
+	if !hasPrefix(skipped, "info ponderhit ") {
+		t.Fatalf("no ponderhit before the reply, got %q", skipped)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("replied %v after a ponder hit that had already used the budget", elapsed)
+	}
+	d.quit()
+}
+
+func TestPonderMiss(t *testing.T) {
+	d := startDriver(t, newPonderSession(t))
+	board := amazon.NewBoard()
+	d.send("new black")
+	d.expectMove(board, amazon.Black)
+
+	// 走出与预测不同的着法，引擎应停止后台思考并重新搜索
+	line, _ := d.expect("info ponder ")
+	guess := strings.TrimPrefix(line, "info ponder ")
+	var m amazon.AmazonMove
+	for _, m = range board.GenerateMoves(amazon.White, nil) {
+		if m.Notation() != guess {
+			break
+		}
+	}
+	board.Move(m)
+	d.send("move " + m.Notation())
+	skipped := d.expectMove(board, amazon.Black)
+	if hasPrefix(skipped, "info ponderhit ") {
+		t.Errorf("ponder hit on a move other than the guess %s: %q", guess, skipped)
+	}
+	if !hasPrefix(skipped, "info depth ") {
+		t.Errorf("no new search after a ponder miss: %q", skipped)
+	}
+	d.quit()
+}
+
+func TestPonderMCTS(t *testing.T) {
+	// 蒙特卡洛树搜索在对手思考期间扩展对手的各应着，对手走棋后沿用对应子树
+	s := newPonderSession(t)
+	s.Algorithm = AlgorithmMCTS
+	d := startDriver(t, s)
+	board := amazon.NewBoard()
+	d.send("new black")
+	d.expectMove(board, amazon.Black)
+	d.expect("info ponder any")
+
+	time.Sleep(500 * time.Millisecond)
+	m := board.GenerateMoves(amazon.White, nil)[0]
+	board.Move(m)
+	d.send("move " + m.Notation())
+	skipped := d.expectMove(board, amazon.Black)
+	var iterations, reused int
+	for _, l := range skipped {
+		if _, err := fmt.Sscanf(l, "info mcts iterations %d reused %d", &iterations, &reused); err == nil {
+			break
+		}
+	}
+	if reused == 0 {
+		t.Errorf("search after pondering reused no simulations: %q", skipped)
+	}
+	d.quit()
+}
@@ -0,0 +1,125 @@
+// 调用博弈树搜索为当前局面选出本方着法。
+package protocol
+
+import (
+	"fmt"
+	"slices"
+	"tamazon/amazon"
+	"time"
+)
+
+/*
+ * search
+ * 按本局剩余用时和空位数分配时间，用迭代加深Alpha-Beta或蒙特卡洛树搜索最佳移动，执行后向平台输出
+ * 所有区域归属已定时改用残局填充，争夺区域足够小时先尝试精确求解
+ * regions为当前局面的区域划分，由调用方每步划分一次
+ * 被平台拒绝过的着法不会再次选出
+ */
+func (s *Session) search(regions amazon.Partition) {
+	// 所有区域归属已定时，直接按残局填充走棋
+	if m, ok := regions.FillingMove(s.color); ok && !slices.Contains(s.rejected, m) {
+		if s.Detail {
+			black, white, _ := regions.FillingCounts()
+			fmt.Fprintf(s.out, "info filling black %d white %d move %s\n", black, white, m.Notation())
+		}
+		s.play(m)
+		return
+	}
+
+	start := time.Now()
+	budget := s.clock.Budget(s.board)
+
+	// 争夺区域足够小时先用一半时间精确求解，证明必胜则直接走致胜着法
+	if regions.Solvable() {
+		win, m, ok := s.board.SolveRegions(regions, s.color, start.Add(budget/2))
+		if ok && win && !slices.Contains(s.rejected, m) {
+			s.clock.Spend(time.Since(start))
+			if s.Detail {
+				fmt.Fprintf(s.out, "info solved win time %v move %s\n", time.Since(start).Round(time.Millisecond), m.Notation())
+			}
+			s.play(m)
+			return
+		}
+	}
+	if s.Algorithm == AlgorithmMCTS {
+		s.searchMCTS(start, budget)
+		return
+	}
+	if s.tt == nil {
+		s.tt = amazon.NewTransTable(amazon.DefaultTTSizeMB)
+	}
+	opts := amazon.SearchOptions{
+		Step:     s.step,
+		Deadline: start.Add(budget),
+		Exclude:  s.rejected,
+		TT:       s.tt,
+		Threads:  s.Threads,
+		Eval:     s.evaluator(),
+	}
+	if s.Detail {
+		opts.Info = func(r amazon.SearchResult) {
+			fmt.Fprintf(s.out, "info depth %d score %.2f nodes %d time %v move %s\n",
+				r.Depth, r.Score, r.Nodes, r.Elapsed.Round(time.Millisecond), r.Move.Notation())
+		}
+	}
+	result, ok := s.board.Search(s.color, opts)
+	s.clock.Spend(time.Since(start))
+	if !ok {
+		return
+	}
+	s.play(result.Move)
+}
+
+// 用蒙特卡洛树搜索选出着法，时间预算由搜索器按选项动态分配
+// 搜索树在整局内保留，双方着法都经Advance下行，本次搜索从当前局面对应的节点继续
+func (s *Session) searchMCTS(start time.Time, budget time.Duration) {
+	opts := s.mctsOptions()
+	opts.Step = s.step
+	opts.Budget = budget
+	opts.Exclude = s.rejected
+	if s.mcts == nil {
+		s.mcts = amazon.NewMCTS(opts)
+	} else {
+		s.mcts.SetOptions(opts)
+	}
+	result, ok := s.mcts.Search(s.board, s.color)
+	s.clock.Spend(time.Since(start))
+	if !ok {
+		return
+	}
+	if s.Detail {
+		fmt.Fprintf(s.out, "info mcts iterations %d reused %d score %.3f time %v move %s\n",
+			result.Iterations, result.Reused, result.Score, result.Elapsed.Round(time.Millisecond), result.Move.Notation())
+	}
+	s.play(result.Move)
+}
+
+// 蒙特卡洛树搜索的基本设置，评估器和线程数取自会话
+func (s *Session) mctsOptions() amazon.MCTSOptions {
+	opts := amazon.DefaultMCTSOptions
+	if s.MCTS != nil {
+		opts = *s.MCTS
+	}
+	opts.Eval = s.evaluator()
+	opts.Threads = s.Threads
+	return opts
+}
+
+// 执行本方着法，向平台输出并记录，然后开始后台思考
+func (s *Session) play(m amazon.AmazonMove) {
+	s.before = *s.board
+	s.lastMove = &m
+	s.board.Move(m)
+	s.treeBefore = amazon.MCTSCheckpoint{}
+	if s.mcts != nil {
+		s.treeBefore = s.mcts.Checkpoint()
+		s.mcts.Advance(m)
+	}
+	// 输出移动信息
+	fmt.Fprintf(s.out, "move %s\n", m.Notation())
+	// 记录游戏
+	s.Record.AddMove(m)
+	// 更新步数
+	s.step++
+	s.startPonder()
+}
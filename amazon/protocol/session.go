@@ -0,0 +1,259 @@
+// 实现SAU平台通信协议，一个Session对应一条输入输出通道上的完整对局状态。
+package protocol
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"tamazon/amazon"
+	"time"
+)
+
+// 可选的搜索算法
+const (
+	AlgorithmAlphaBeta = "ab"   // 迭代加深Alpha-Beta
+	AlgorithmMCTS      = "mcts" // 蒙特卡洛树搜索
+)
+
+// Session 保存一个引擎会话的全部状态，多个会话之间互不影响
+type Session struct {
+	Name      string              // 应答"name?"时使用的引擎名称
+	Detail    bool                // 是否输出搜索详细信息
+	GameTime  time.Duration       // 本方整局总用时，0表示使用amazon.DefaultGameTime
+	Params    *amazon.EvalParams  // 评估参数，为nil时使用amazon.DefaultEvalParams
+	Eval      amazon.Evaluator    // 搜索使用的评估器，为nil时按Params使用手工评估函数
+	Algorithm string              // 搜索算法，AlgorithmAlphaBeta（默认）或AlgorithmMCTS
+	MCTS      *amazon.MCTSOptions // 蒙特卡洛树搜索的设置，为nil时使用amazon.DefaultMCTSOptions
+	Threads   int                 // 搜索线程数，0或1表示单线程
+	Ponder    bool                // 是否在对手思考期间后台搜索
+	Record    amazon.GameRecord   // 对局记录
+
+	in  io.Reader
+	out io.Writer
+
+	board      *amazon.AmazonBoard   // 棋盘，未开局时为nil
+	color      int                   // 本方颜色
+	step       int                   // 当前步数
+	clock      amazon.Clock          // 本方剩余用时
+	tt         *amazon.TransTable    // 置换表，在整局的各次搜索间复用
+	lastMove   *amazon.AmazonMove    // 本方最近一次发出的着法，用于"error"回滚
+	before     amazon.AmazonBoard    // 本方最近一次着法之前的棋盘
+	treeBefore amazon.MCTSCheckpoint // 本方最近一次着法之前的搜索树
+	rejected   []amazon.AmazonMove   // 被平台判为错误的着法，重新搜索时跳过
+	mcts       *amazon.MCTS          // 蒙特卡洛树搜索器，搜索树在整局内保留
+	pondering  *ponder               // 进行中的后台思考
+}
+
+// 创建一个从in读取平台命令、向out输出应答的会话
+func NewSession(name string, in io.Reader, out io.Writer) *Session {
+	return &Session{
+		Name: name,
+		in:   in,
+		out:  out,
+	}
+}
+
+/*
+ * Run
+ * 逐行读取并处理平台命令，直到收到"quit"、输入结束或ctx被取消
+ * 输入"new black"或"new white"开始新游戏
+ * 输入"move A1B2C3"进行移动，格式为"move from to put"，非法着法会被拒绝
+ * 输入"error"撤销本方上一步并重新搜索
+ * 输入"end [black|white]"保存游戏记录，可附带获胜方
+ * 输入"perft <depth>"统计当前局面的perft节点数，用于校验步法生成
+ * 输入"eval"输出当前局面评估值的各要素明细和领土归属图
+ * 其余命令（accept、refuse、take、taked等）及未知命令直接忽略
+ */
+func (s *Session) Run(ctx context.Context) error {
+	defer s.stopPonder()
+	// 返回时取消ctx，读取协程阻塞在发送上时随之退出
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	lines := make(chan string)
+	errc := make(chan error, 1)
+	go func() {
+		defer close(lines)
+		sc := bufio.NewScanner(s.in)
+		for sc.Scan() {
+			select {
+			case lines <- sc.Text():
+			case <-ctx.Done():
+				return
+			}
+		}
+		errc <- sc.Err()
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case line, ok := <-lines:
+			if !ok {
+				select {
+				case err := <-errc:
+					return err
+				default:
+					return ctx.Err()
+				}
+			}
+			if !s.handle(line) {
+				return nil
+			}
+		}
+	}
+}
+
+// 处理一条命令，返回false表示会话应当结束
+func (s *Session) handle(line string) bool {
+	words := strings.Fields(line)
+	if len(words) == 0 {
+		return true
+	}
+	switch words[0] {
+	case "name?":
+		fmt.Fprintf(s.out, "name %s\n", s.Name)
+	case "quit":
+		return false
+	case "new":
+		if len(words) < 2 {
+			fmt.Fprintf(s.out, "info invalid new command %q\n", line)
+			return true
+		}
+		// 执子方只能是black或white，其他取值不开新局，当前对局保持不变
+		var color int
+		switch words[1] {
+		case "black":
+			color = amazon.Black
+		case "white":
+			color = amazon.White
+		default:
+			fmt.Fprintf(s.out, "info invalid side %q\n", words[1])
+			return true
+		}
+		s.stopPonder()
+		s.step = 1
+		s.board = amazon.NewBoard()
+		s.clock = amazon.NewClock(s.GameTime)
+		s.lastMove = nil
+		s.rejected = nil
+		s.mcts = nil
+		s.color = color
+		if color == amazon.Black {
+			s.search(s.board.Regions())
+		}
+	case "move":
+		if s.board == nil || len(words) < 2 {
+			fmt.Fprintf(s.out, "info invalid move command %q\n", line)
+			return true
+		}
+		// 校验对手着法，非法着法不落盘，避免引擎崩溃或棋盘被破坏
+		m, err := s.board.ParseLegalMove(words[1], 3-s.color)
+		if err != nil {
+			fmt.Fprintf(s.out, "info invalid move %v\n", err)
+			return true
+		}
+		s.board.Move(m)
+		s.Record.AddMove(m)
+		s.lastMove = nil
+		s.rejected = nil
+		s.step++
+		regions := s.board.Regions() // 本步的填充和求解判断共用一次区域划分
+		if s.ponderHit(m, regions) {
+			return true
+		}
+		if s.mcts != nil {
+			s.mcts.Advance(m)
+		}
+		if !s.board.IsGameOver() {
+			s.search(regions)
+		}
+	case "error":
+		// 平台判定本方上一步错误：恢复棋盘后重新搜索，并排除该着法
+		if s.board == nil || s.lastMove == nil {
+			return true
+		}
+		s.stopPonder()
+		*s.board = s.before
+		if s.mcts != nil {
+			s.mcts.Restore(s.treeBefore)
+		}
+		s.rejected = append(s.rejected, *s.lastMove)
+		s.lastMove = nil
+		s.Record.Undo()
+		s.step--
+		s.search(s.board.Regions())
+	case "perft":
+		// 调试命令：从当前局面（未开局时为初始局面）统计perft叶子节点数
+		if len(words) < 2 {
+			fmt.Fprintf(s.out, "info invalid perft command %q\n", line)
+			return true
+		}
+		depth, err := strconv.Atoi(words[1])
+		if err != nil || depth < 0 {
+			fmt.Fprintf(s.out, "info invalid perft command %q\n", line)
+			return true
+		}
+		board, toMove := s.board, amazon.White
+		if board == nil {
+			board = amazon.NewBoard()
+		}
+		if s.board == nil || s.step%2 == 1 {
+			toMove = amazon.Black // 黑方先行，步数为奇数时轮到黑方
+		}
+		start := time.Now()
+		nodes := board.Perft(toMove, depth)
+		fmt.Fprintf(s.out, "perft %d %d time %v\n", depth, nodes, time.Since(start).Round(time.Millisecond))
+	case "eval":
+		// 调试命令：拆解当前局面（未开局时为初始局面）的评估值
+		board := s.board
+		if board == nil {
+			board = amazon.NewBoard()
+		}
+		board.WriteEvalReport(s.out, s.params(), s.step)
+		if s.Eval != nil {
+			fmt.Fprintf(s.out, "evaluator %.3f\n", s.Eval.Evaluate(board, s.step, amazon.Black))
+		}
+	case "end":
+		winner := amazon.Empty
+		if len(words) > 1 {
+			winner = parseSide(words[1])
+		}
+		s.stopPonder()
+		s.Record.Save(winner)
+		s.board = nil
+		s.mcts = nil
+		s.lastMove = nil
+	}
+	return true
+}
+
+// 将"black"/"white"转换为棋子颜色，无法识别时返回Empty
+func parseSide(side string) int {
+	switch side {
+	case "black":
+		return amazon.Black
+	case "white":
+		return amazon.White
+	}
+	return amazon.Empty
+}
+
+// 手工评估函数的参数
+func (s *Session) params() *amazon.EvalParams {
+	if s.Params == nil {
+		return &amazon.DefaultEvalParams
+	}
+	return s.Params
+}
+
+// 搜索使用的评估器
+func (s *Session) evaluator() amazon.Evaluator {
+	if s.Eval != nil {
+		return s.Eval
+	}
+	return s.params()
+}
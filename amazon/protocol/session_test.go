@@ -0,0 +1,172 @@
+package protocol
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"tamazon/amazon"
+	"testing"
+	"time"
+)
+
+// 对所有局面给出相同分值的评估器，使Alpha-Beta总是选出静态预评分排序后的第一个根着法，
+// 结果与搜索深度无关，便于脚本化测试预先算出引擎的应着
+type flatEval struct{}
+
+func (flatEval) Evaluate(*amazon.AmazonBoard, int, int) float64 { return 0 }
+
+// 引擎在局面b上为color一方选出的着法，exclude为被拒绝过的着法
+func expectedMove(t *testing.T, b *amazon.AmazonBoard, color int, exclude ...amazon.AmazonMove) amazon.AmazonMove {
+	t.Helper()
+	result, ok := b.Search(color, amazon.SearchOptions{MaxDepth: 1, Eval: flatEval{}, Exclude: exclude})
+	if !ok {
+		t.Fatalf("no move for color %d", color)
+	}
+	return result.Move
+}
+
+// 新建一个以flatEval搜索、总用时极短的会话，对局记录写入临时目录
+func newTestSession(t *testing.T, transcript string, out *bytes.Buffer) *Session {
+	s := NewSession("test", strings.NewReader(transcript), out)
+	s.Eval = flatEval{}
+	s.GameTime = time.Second
+	s.Record.Dir = t.TempDir()
+	return s
+}
+
+func TestSessionTranscript(t *testing.T) {
+	// 预先推演对局：引擎执黑先走，对手随后走出其第一个合法着法，引擎应着后被平台判错并重新搜索
+	board := amazon.NewBoard()
+	first := expectedMove(t, board, amazon.Black)
+	board.Move(first)
+	reply := board.GenerateMoves(amazon.White, nil)[0]
+	board.Move(reply)
+	second := expectedMove(t, board, amazon.Black)
+	retry := expectedMove(t, board, amazon.Black, second)
+	if retry == second {
+		t.Fatalf("retry %s equals the rejected move", retry.Notation())
+	}
+
+	transcript := strings.Join([]string{
+		"name?",
+		"new black",
+		"move XYZ",                 // 格式错误
+		"move " + first.Notation(), // 黑方着法，对白方不合法
+		"move " + reply.Notation(),
+		"error",
+		"end black",
+		"quit",
+		"name?", // quit之后的命令不再处理
+	}, "\n")
+	var out bytes.Buffer
+	s := newTestSession(t, transcript, &out)
+	if err := s.Run(context.Background()); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	want := []string{
+		"name test",
+		"move " + first.Notation(),
+		"info invalid move ",
+		"info invalid move ",
+		"move " + second.Notation(),
+		"move " + retry.Notation(),
+	}
+	if len(lines) != len(want) {
+		t.Fatalf("output has %d lines, want %d:\n%s", len(lines), len(want), out.String())
+	}
+	for i, w := range want {
+		if !strings.HasPrefix(lines[i], w) {
+			t.Errorf("line %d = %q, want prefix %q", i, lines[i], w)
+		}
+	}
+	if s.board != nil {
+		t.Error("board not cleared after end")
+	}
+
+	files, err := os.ReadDir(s.Record.Dir)
+	if err != nil || len(files) != 1 {
+		t.Fatalf("record dir has %d files (%v), want 1", len(files), err)
+	}
+	if !strings.Contains(files[0].Name(), "先手胜") {
+		t.Errorf("record %q does not name black as winner", files[0].Name())
+	}
+}
+
+func TestSessionInputEnd(t *testing.T) {
+	// 没有quit时读到输入结束即返回
+	var out bytes.Buffer
+	s := newTestSession(t, "name?\n", &out)
+	if err := s.Run(context.Background()); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if got := out.String(); got != "name test\n" {
+		t.Errorf("output = %q", got)
+	}
+}
+
+func TestSessionNewSide(t *testing.T) {
+	// 执子方不是black或white时拒绝开局并报告该取值，已有对局不受影响
+	var out bytes.Buffer
+	s := newTestSession(t, "new red\nnew white\nnew\nnew Black\n", &out)
+	if err := s.Run(context.Background()); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	want := "info invalid side \"red\"\ninfo invalid new command \"new\"\ninfo invalid side \"Black\"\n"
+	if got := out.String(); got != want {
+		t.Errorf("output = %q, want %q", got, want)
+	}
+	if s.board == nil || s.color != amazon.White || s.step != 1 {
+		t.Errorf("after rejected commands: board %v, color %d, step %d; want the white game kept", s.board != nil, s.color, s.step)
+	}
+}
+
+func TestSessionCancel(t *testing.T) {
+	// ctx被取消时即使输入未结束也立即返回
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	defer r.Close()
+	s := NewSession("test", r, new(bytes.Buffer))
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- s.Run(ctx) }()
+	cancel()
+	select {
+	case err := <-done:
+		if err != context.Canceled {
+			t.Errorf("Run = %v, want %v", err, context.Canceled)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run did not return after cancel")
+	}
+}
+
+func TestSessionEval(t *testing.T) {
+	// 未开局时拆解初始局面；对局中拆解当前局面，阶段按当前步数选取
+	board := amazon.NewBoard()
+	var want bytes.Buffer
+	board.WriteEvalReport(&want, &amazon.DefaultEvalParams, 0)
+	want.WriteString("evaluator 0.000\n")
+	opening := board.GenerateMoves(amazon.Black, nil)[0]
+	board.Move(opening)
+	reply := expectedMove(t, board, amazon.White)
+	board.Move(reply)
+	fmt.Fprintf(&want, "move %s\n", reply.Notation())
+	board.WriteEvalReport(&want, &amazon.DefaultEvalParams, 3)
+	want.WriteString("evaluator 0.000\n")
+
+	var out bytes.Buffer
+	s := newTestSession(t, "eval\nnew white\nmove "+opening.Notation()+"\neval\nquit\n", &out)
+	if err := s.Run(context.Background()); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if out.String() != want.String() {
+		t.Errorf("output:\n%s\nwant:\n%s", out.String(), want.String())
+	}
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"math/rand"
+	"os"
+	"tamazon/amazon"
+	"time"
+)
+
+/*
+* selfplay 子命令
+* 引擎与自身对弈若干局，每局开局随机走几步，之后用Alpha-Beta或蒙特卡洛树搜索选择着法
+* 搜索过的局面连同分值、着法和胜负逐行写入JSONL文件，可直接交给tune使用
+* 用法：tamazon selfplay [-games n] [-o selfplay.jsonl] [-movetime 1s|-depth d] [-mcts] [-threads n] [-random 4] [-seed s]
+ */
+func runSelfPlay(args []string) int {
+	flags := flag.NewFlagSet("selfplay", flag.ExitOnError)
+	games := flags.Int("games", 10, "对局数")
+	outPath := flags.String("o", "selfplay.jsonl", "输出文件，已存在时追加")
+	depth := flags.Int("depth", 0, "固定搜索深度，0表示按-movetime限时")
+	moveTime := flags.Duration("movetime", time.Second, "每步搜索时间")
+	randomPlies := flags.Int("random", 4, "开局随机走的步数")
+	seed := flags.Int64("seed", 0, "随机数种子，0表示按当前时间")
+	paramsPath := flags.String("eval-config", "", "评估参数文件，默认使用内置参数")
+	netPath := flags.String("nn", "", "估值网络权重文件，指定时使用估值网络评估")
+	mcts := flags.Bool("mcts", false, "使用蒙特卡洛树搜索（默认设置，按-movetime限时），记录根节点访问次数")
+	threads := flags.Int("threads", 1, "搜索线程数")
+	flags.Parse(args)
+
+	var eval amazon.Evaluator = &amazon.DefaultEvalParams
+	if *paramsPath != "" {
+		p, err := amazon.LoadEvalParams(*paramsPath)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		eval = &p
+	}
+	if *netPath != "" {
+		n, err := amazon.LoadNetwork(*netPath)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		eval = n
+	}
+	if *seed == 0 {
+		*seed = time.Now().UnixNano()
+	}
+
+	f, err := os.OpenFile(*outPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	defer f.Close()
+	w := bufio.NewWriter(f)
+
+	tt := amazon.NewTransTable(amazon.DefaultTTSizeMB)
+	r := rand.New(rand.NewSource(*seed))
+	choose := func(b *amazon.AmazonBoard, color, turn int) (amazon.AmazonMove, float64, map[amazon.AmazonMove]int, bool) {
+		if *mcts {
+			opts := amazon.DefaultMCTSOptions
+			opts.Step, opts.Budget, opts.Eval, opts.Seed, opts.Threads = turn, *moveTime, eval, r.Int63(), *threads
+			res, ok := amazon.NewMCTS(opts).Search(b, color)
+			return res.Move, res.Score, res.Visits, ok
+		}
+		opts := amazon.SearchOptions{Step: turn, MaxDepth: *depth, TT: tt, Eval: eval, Threads: *threads}
+		if *depth == 0 {
+			opts.Deadline = time.Now().Add(*moveTime)
+		}
+		res, ok := b.Search(color, opts)
+		return res.Move, res.Score, nil, ok
+	}
+	scale := amazon.ScaleEval
+	if *mcts {
+		scale = amazon.ScaleWinRate
+	}
+	fmt.Printf("seed %d\n", *seed)
+	for g := 1; g <= *games; g++ {
+		tt.Clear()
+		start := time.Now()
+		positions, winner := amazon.SelfPlay(amazon.SelfPlayOptions{RandomPlies: *randomPlies, Choose: choose, Scale: scale}, r)
+		if err := amazon.WriteSelfPlay(w, positions); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		if err := w.Flush(); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		fmt.Printf("game %d winner %d positions %d time %v\n", g, winner, len(positions), time.Since(start).Round(time.Millisecond))
+	}
+	return 0
+}
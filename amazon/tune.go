@@ -0,0 +1,117 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"tamazon/amazon"
+)
+
+/*
+* tune 子命令
+* 读取棋谱文件和自对弈数据（或目录下的全部.txt棋谱和.jsonl数据），以对局结果为标签拟合评估参数，结果写入JSON文件
+* 用法：tamazon tune [-init eval.json] [-o eval.json] [-passes n] 棋谱、数据或目录...
+ */
+func runTune(args []string) int {
+	flags := flag.NewFlagSet("tune", flag.ExitOnError)
+	initPath := flags.String("init", "", "初始评估参数文件，默认使用内置参数")
+	outPath := flags.String("o", "eval.json", "输出的评估参数文件")
+	passes := flags.Int("passes", 100, "最多遍历轮数，0表示直到收敛")
+	flags.Parse(args)
+	if flags.NArg() == 0 {
+		fmt.Fprintln(os.Stderr, "usage: tamazon tune [flags] record-or-jsonl-file-or-dir...")
+		flags.PrintDefaults()
+		return 2
+	}
+
+	params := amazon.DefaultEvalParams
+	if *initPath != "" {
+		p, err := amazon.LoadEvalParams(*initPath)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		params = p
+	}
+
+	files, err := recordFiles(flags.Args())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	var samples []amazon.TuneSample
+	loaded := 0
+	for _, path := range files {
+		s, err := loadSamples(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "skip %v\n", err)
+			continue
+		}
+		if len(s) > 0 {
+			samples = append(samples, s...)
+			loaded++
+		}
+	}
+	if len(samples) == 0 {
+		fmt.Fprintln(os.Stderr, "no labeled positions found")
+		return 1
+	}
+	fmt.Printf("files %d positions %d\n", loaded, len(samples))
+
+	tuned, loss := amazon.Tune(params, samples, amazon.TuneOptions{
+		MaxPasses: *passes,
+		Log: func(pass int, loss float64) {
+			fmt.Printf("pass %d loss %.6f\n", pass, loss)
+		},
+	})
+	if err := tuned.Save(*outPath); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	fmt.Printf("loss %.6f saved %s\n", loss, *outPath)
+	return 0
+}
+
+// 读取一个文件中的训练样本，.jsonl为自对弈数据，其余按棋谱读取
+func loadSamples(path string) ([]amazon.TuneSample, error) {
+	if strings.HasSuffix(path, ".jsonl") {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		positions, err := amazon.ReadSelfPlay(f)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		return amazon.SelfPlaySamples(positions)
+	}
+	moves, winner, err := amazon.LoadRecord(path)
+	if err != nil {
+		return nil, err
+	}
+	return amazon.GameSamples(moves, winner), nil
+}
+
+// 展开命令行中的路径，目录按字典序递归收集其中的.txt棋谱和.jsonl自对弈数据
+func recordFiles(paths []string) ([]string, error) {
+	var files []string
+	for _, root := range paths {
+		err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if !d.IsDir() && (path == root || strings.HasSuffix(path, ".txt") || strings.HasSuffix(path, ".jsonl")) {
+				files = append(files, path)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return files, nil
+}